@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strconv"
+	"time"
+)
+
+// aggregatef compares the word counts summed per case and type over the
+// invoiced period instead of matching rows, for shuhos that log a case over
+// several rows the invoice bills as one line, or the other way around
+var aggregatef bool
+
+// checkEnabled leaves out the row matching checks with --aggregate and the
+// case-totals check without it
+func checkEnabled(name string) bool {
+	switch name {
+	case "invoice-in-shuho", "shuho-in-invoice":
+		return !aggregatef
+	case "case-totals":
+		return aggregatef
+	}
+
+	return true
+}
+
+// caseTotal is the words of a case and type on one side and the rows they come from
+type caseTotal struct {
+	words int
+	rows  []Entry
+}
+
+func sumCaseTotals(entries []Entry) map[string]*caseTotal {
+	totals := make(map[string]*caseTotal)
+	for _, e := range entries {
+		words, err := strconv.Atoi(e.WordCount())
+		if err != nil {
+			continue
+		}
+		key := matchCase(e.CaseNum()) + " " + e.Type()
+		if totals[key] == nil {
+			totals[key] = &caseTotal{}
+		}
+		totals[key].words += words
+		totals[key].rows = append(totals[key].rows, e)
+	}
+
+	return totals
+}
+
+// ensureCaseTotalsMatch reports every case and type whose words on the
+// invoice don't add up to its words in the shuho over the invoiced months,
+// at the first row of it
+func ensureCaseTotalsMatch(sentries []Entry, ientries []Entry) []Finding {
+	first, last, ok := invoicePeriod(ientries)
+	if !ok {
+		return nil
+	}
+	invoiced := sumCaseTotals(matchable(ientries))
+	//the whole invoiced months, a case split differently may start before the first invoiced day
+	start := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(last.Year(), last.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	var period []Entry
+	for _, e := range sentries {
+		if !e.Date().Before(start) && e.Date().Before(end) {
+			period = append(period, e)
+		}
+	}
+	logged := sumCaseTotals(matchable(period))
+
+	keys := make(map[string]bool)
+	for key := range invoiced {
+		keys[key] = true
+	}
+	for key := range logged {
+		keys[key] = true
+	}
+
+	var findings []Finding
+	for _, key := range sortedKeys(keys) {
+		i, s := invoiced[key], logged[key]
+		if i == nil {
+			i = &caseTotal{}
+		}
+		if s == nil {
+			s = &caseTotal{}
+		}
+		if i.words == s.words {
+			continue
+		}
+		first := append(append([]Entry(nil), i.rows...), s.rows...)[0]
+		findings = append(findings, Finding{
+			Message: printer.Sprintf("%s %s totals %d words in %d invoice rows but %d words in %d shuho rows",
+				first.CaseNum(), typeLabel(first.Type()), i.words, len(i.rows), s.words, len(s.rows)),
+			Entry: first,
+		})
+	}
+
+	return findings
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// anonymizeCommand replaces case numbers, authors and rates with synthetic
+// values so a workbook can be attached to a bug report. Dates, types, word
+// counts and the sheet layout are kept as they are. A rate that is right for
+// its row becomes the default rate of its type and a wrong one a made-up
+// rate, so the report of the copies without the config has the same
+// findings. A shuho and its invoice are anonymized in one run to keep their
+// case numbers matching.
+func anonymizeCommand(args []string) {
+	fs := flag.NewFlagSet("anonymize", flag.ExitOnError)
+	kind := fs.String("kind", "", "workbook kind: shuho or invoice (detected per workbook when empty)")
+	fs.BoolVar(&noBackupf, "no-backup", false, "overwrite an existing out.xlsx without a timestamped copy of it")
+	checkFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() == 0 || fs.NArg()%2 != 0 {
+		printer.Fprintf(out, "\033[1;31mERROR Usage:\033[0m ./verifyshuho anonymize [--kind shuho|invoice] <in.xlsx> <out.xlsx> [<in.xlsx> <out.xlsx>...]\n")
+		return
+	}
+
+	//the column layouts and the rates the rows are read with
+	if err := loadSettings(); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return
+	}
+
+	a := newAnonymizer()
+	for i := 0; i < fs.NArg(); i += 2 {
+		if err := a.anonymizeFile(fs.Arg(i), fs.Arg(i+1), *kind); err != nil {
+			fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+			return
+		}
+	}
+}
+
+// anonymizer hands out the synthetic values in the order the originals are
+// met, the same original always gets the same one
+type anonymizer struct {
+	cases, authors, rates map[string]string
+}
+
+func newAnonymizer() *anonymizer {
+	return &anonymizer{cases: make(map[string]string), authors: make(map[string]string), rates: make(map[string]string)}
+}
+
+func (a *anonymizer) anonymizeFile(in, output, kind string) error {
+	f, err := excelize.OpenFile(in)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		}
+	}()
+
+	if kind == "" {
+		kind = detectWorkbookKind(xlsxWorkbook{f})
+	}
+
+	var changed int
+	switch kind {
+	case "shuho":
+		changed, err = a.shuho(f)
+	case "invoice":
+		changed, err = a.invoice(f)
+	default:
+		return fmt.Errorf("unknown workbook kind %q", kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := saveWorkbook(f, output); err != nil {
+		return err
+	}
+
+	showCheckSuccess(printer.Sprintf("Anonymized %s workbook, %d cells changed -> %s", kind, changed, output))
+	return nil
+}
+
+func (a *anonymizer) caseNumber(value string) string {
+	if _, ok := a.cases[value]; !ok {
+		a.cases[value] = fmt.Sprintf("ALP-%05d", len(a.cases)+1)
+	}
+	return a.cases[value]
+}
+
+func (a *anonymizer) author(value string) string {
+	if _, ok := a.authors[value]; !ok {
+		a.authors[value] = fmt.Sprintf("Author-%03d", len(a.authors)+1)
+	}
+	return a.authors[value]
+}
+
+// rate is the synthetic rate of an invoice entry: the default rate of its
+// type when its rate is the expected one, the default rate of the type whose
+// rate it has by mistake, else a made-up rate in no rate table
+func (a *anonymizer) rate(ie InvoiceEntry) string {
+	if expected, ok := expectedRate(ie); ok && sameRate(ie.rate, expected) {
+		if rate, ok := defaultInvoiceRates[ie.IType]; ok {
+			return rate
+		}
+	}
+	for _, eType := range sortedKeys(invoiceRates) {
+		if sameRate(ie.rate, invoiceRates[eType]) && defaultInvoiceRates[eType] != "" {
+			return defaultInvoiceRates[eType]
+		}
+	}
+	if _, ok := a.rates[ie.rate]; !ok {
+		a.rates[ie.rate] = strconv.Itoa(100 + len(a.rates))
+	}
+	return a.rates[ie.rate]
+}
+
+func (a *anonymizer) shuho(f *excelize.File) (int, error) {
+	var changed int
+
+	for _, name := range f.GetSheetList() {
+		rows, err := f.GetRows(name)
+		if err != nil {
+			return changed, err
+		}
+		columns := shuhoColumnsFor(name, rows)
+
+		//only touch data rows, headers and notes stay readable
+		var dated bool
+		for r, row := range rows {
+			mapped := columns.apply(row)
+			date := mapped[0]
+			if cancelledDateRe.MatchString(date) {
+				date = strings.TrimSpace(date[1:])
+			}
+			//later rows of the same day may leave the date empty
+			if checkForValidDate(date) {
+				dated = true
+			} else if date != "" {
+				dated = false
+			}
+			if !dated {
+				continue
+			}
+
+			if value := mapped[1]; !checkForEmptyCase(value) {
+				if err := setCellStr(f, name, columns, "case", r, a.caseNumber(strings.ReplaceAll(value, ",", ""))); err != nil {
+					return changed, err
+				}
+				changed++
+			}
+			if value := mapped[6]; value != "" {
+				if err := setCellStr(f, name, columns, "author", r, a.author(value)); err != nil {
+					return changed, err
+				}
+				changed++
+			}
+		}
+	}
+
+	return changed, nil
+}
+
+func (a *anonymizer) invoice(f *excelize.File) (int, error) {
+	var changed int
+
+	for _, name := range f.GetSheetList() {
+		rows, err := f.GetRows(name)
+		if err != nil {
+			return changed, err
+		}
+		columns := invoiceColumnsf
+		if _, detected, known := detectInvoiceLayout(rows); known && !invoiceColumnsf.set {
+			columns = detected
+		}
+
+		for r, row := range rows {
+			mapped := columns.apply(row)
+			date, err := getDate(mapped[3])
+			if !invoiceDateRe.MatchString(mapped[3]) || err != nil {
+				continue
+			}
+
+			if value := mapped[1]; !checkForEmptyCase(value) {
+				if err := setCellStr(f, name, columns, "case", r, a.caseNumber(strings.ReplaceAll(value, ",", ""))); err != nil {
+					return changed, err
+				}
+				changed++
+			}
+			if mapped[5] == "" {
+				continue
+			}
+			ie := InvoiceEntry{ICaseNum: mapped[1], IType: canonicalType(mapped[2], name, date), IDate: date, IWordCount: normalizeNumber(mapped[4]), rate: normalizeNumber(mapped[5]), client: strings.TrimSpace(mapped[7])}
+			cell, _ := excelize.CoordinatesToCellName(columns.cols[columns.field("rate")]+1, r+1)
+			rate, _ := strconv.ParseFloat(a.rate(ie), 64)
+			if err := f.SetCellFloat(name, cell, rate, -1, 64); err != nil {
+				return changed, err
+			}
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+// setCellStr sets the cell of field in the zero based row r
+func setCellStr(f *excelize.File, sheet string, columns *columnMapping, field string, r int, value string) error {
+	cell, err := excelize.CoordinatesToCellName(columns.cols[columns.field(field)]+1, r+1)
+	if err != nil {
+		return err
+	}
+
+	return f.SetCellStr(sheet, cell, value)
+}
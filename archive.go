@@ -0,0 +1,109 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// the files of an archive verified as invoices, anything else in it is skipped
+var archiveInvoiceExts = map[string]bool{".xlsx": true, ".xlsm": true, ".xls": true, ".ods": true, ".json": true, ".csv": true}
+
+// archiveResult is the outcome for one invoice of an archive
+type archiveResult struct {
+	name             string
+	entries          int
+	errors, warnings int
+	pretax           float64
+	status           int
+}
+
+// verifyArchive verifies every invoice in the zip at archivePath against the
+// shuho, one report after the other and a summary per invoice at the end.
+// The exit status is the worst of the invoices'.
+func verifyArchive(fshuho Workbook, shuhoInput inputFile, archivePath string) int {
+	z, err := zip.OpenReader(archivePath)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	defer z.Close()
+
+	var members []*zip.File
+	for _, member := range z.File {
+		//macOS adds resource forks of every file when zipping
+		if member.FileInfo().IsDir() || strings.HasPrefix(member.Name, "__MACOSX/") || strings.HasPrefix(path.Base(member.Name), ".") {
+			continue
+		}
+		if archiveInvoiceExts[strings.ToLower(path.Ext(member.Name))] {
+			members = append(members, member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+	if len(members) == 0 {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m No invoices in %s\n", archivePath)
+		return 2
+	}
+
+	var results []archiveResult
+	worst := 0
+	for _, member := range members {
+		name := archivePath + ":" + member.Name
+		printer.Fprintf(out, "\n==== %s ====\n", name)
+
+		status := verifyArchiveMember(fshuho, shuhoInput, name, member)
+		results = append(results, archiveResult{name, stats.InvoiceEntries, stats.Errors, stats.Warnings, stats.Pretax, status})
+		if status > worst {
+			worst = status
+		}
+	}
+
+	printArchiveSummary(results)
+
+	return worst
+}
+
+func verifyArchiveMember(fshuho Workbook, shuhoInput inputFile, name string, member *zip.File) int {
+	stats = runStats{Findings: make(map[string]int)}
+
+	r, err := member.Open()
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	finvoice, err := dataWorkbook(name, data)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 2
+	}
+	defer finvoice.Close()
+
+	return verifyWorkbooks(fshuho, finvoice, shuhoInput, inputFile{name, fmt.Sprintf("%x", sha256.Sum256(data))})
+}
+
+func printArchiveSummary(results []archiveResult) {
+	colorize(ColorGreen, translate("\n** Per invoice: "))
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", translate("Invoice"), translate("Entries"), translate("Errors"), translate("Warnings"), translate("Pre-tax total"), translate("Result"))
+	for _, r := range results {
+		result := translate("passed")
+		if r.status != 0 {
+			result = translate("failed")
+		}
+		printer.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\n", r.name, r.entries, r.errors, r.warnings, formatYen(r.pretax), result)
+	}
+	w.Flush()
+}
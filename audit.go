@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// auditRecord is one run in the --audit-log file. Prev is the SHA-256 of the
+// line before it without the newline, so editing or dropping an earlier
+// record breaks the chain.
+type auditRecord struct {
+	Time     string         `json:"time"`
+	Shuho    inputFile      `json:"shuho"`
+	Invoice  inputFile      `json:"invoice"`
+	Totals   auditTotals    `json:"totals"`
+	Findings []auditFinding `json:"findings"`
+	// 0 passed, 1 failed
+	ExitStatus int    `json:"exit_status"`
+	Prev       string `json:"prev"`
+}
+
+// inputFile is a verified workbook and its checksum, to tie reports and
+// records to the exact version of the file
+type inputFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// inputFiles are the shuho and invoice of this run, for the reports
+var inputFiles []inputFile
+
+type auditTotals struct {
+	Translations float64 `json:"translations"`
+	Checks       float64 `json:"checks"`
+	Pretax       float64 `json:"pretax"`
+}
+
+type auditFinding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+var auditLogf string
+
+// readChecksums are the SHA-256 of the workbooks read from stdin or a URL by
+// their argument, those can't be read a second time for the checksum
+var readChecksums = make(map[string]string)
+
+func newInputFile(path string) (inputFile, error) {
+	sum, err := fileChecksum(path)
+	if path == "-" {
+		path = stdinName
+	}
+
+	return inputFile{path, sum}, err
+}
+
+// fileChecksum is the hex SHA-256 of the file at path, or of what was read for - or a URL
+func fileChecksum(path string) (string, error) {
+	if sum, ok := readChecksums[path]; ok {
+		return sum, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func newAuditRecord(shuho, invoice inputFile, invoiceEntries []Entry, results []CheckResult, status int) auditRecord {
+	record := auditRecord{Time: now().Format("2006-01-02T15:04:05Z07:00"), Shuho: shuho, Invoice: invoice, ExitStatus: status, Findings: []auditFinding{}}
+
+	translations, checks, pretax := invoiceTotals(invoiceEntries)
+	record.Totals = auditTotals{translations, checks, pretax}
+
+	for _, result := range results {
+		for _, finding := range result.Findings {
+			message := ansiEscapeRe.ReplaceAllString(finding.Message, "")
+			record.Findings = append(record.Findings, auditFinding{result.ID, finding.Severity.String(), finding.Location().String(), message})
+		}
+	}
+
+	return record
+}
+
+// appendAuditRecord chains record to the last line of the log at path and appends it
+func appendAuditRecord(path string, record auditRecord) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if lines := bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n")); len(lines[len(lines)-1]) > 0 {
+		sum := sha256.Sum256(lines[len(lines)-1])
+		record.Prev = hex.EncodeToString(sum[:])
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,74 @@
+package main
+
+// maxAuthorTypo is how many letters apart a name can be from an allowed
+// author to be taken for a typo of it
+const maxAuthorTypo = 2
+
+// ensureKnownAuthors reports shuho rows whose author isn't one of the
+// authors config, a misspelled name silently drops out of everything
+// keyed by the author
+func ensureKnownAuthors(sentries, ientries []Entry, authors []string) []Finding {
+	if len(authors) == 0 {
+		return nil
+	}
+	allowed := make(map[string]bool)
+	for _, author := range authors {
+		allowed[author] = true
+	}
+
+	var findings []Finding
+	for _, entry := range getScopedShuho(sentries, ientries) {
+		se, ok := entry.(ShuhoEntry)
+		if !ok || allowed[se.SAuthor] {
+			continue
+		}
+		message := printer.Sprintf("Author %q is not in the authors list at %s: %s", se.SAuthor, se.Location(), se.String())
+		if author, ok := closestAuthor(se.SAuthor, authors); ok {
+			message = printer.Sprintf("Author %q is not in the authors list, %q misspelled? At %s: %s", se.SAuthor, author, se.Location(), se.String())
+		}
+		findings = append(findings, Finding{Message: message, Entry: se, Severity: SeverityError})
+	}
+
+	return findings
+}
+
+// closestAuthor is the allowed author within maxAuthorTypo edits of name
+func closestAuthor(name string, authors []string) (string, bool) {
+	best, distance := "", maxAuthorTypo+1
+	for _, author := range authors {
+		if d := editDistance(name, author); d < distance {
+			best, distance = author, d
+		}
+	}
+
+	return best, best != ""
+}
+
+// editDistance is the Levenshtein distance of a and b in characters
+func editDistance(a, b string) int {
+	x, y := []rune(a), []rune(b)
+	previous := make([]int, len(y)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		current := make([]int, len(y)+1)
+		current[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous = current
+	}
+
+	return previous[len(y)]
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// noBackupf skips the copy of a workbook made before it is overwritten
+var noBackupf bool
+
+// backupPath is name.backup-20060102-150405.xlsx next to path
+func backupPath(path string, t time.Time) string {
+	ext := filepath.Ext(path)
+
+	return strings.TrimSuffix(path, ext) + ".backup-" + t.Format("20060102-150405") + ext
+}
+
+// backupFile copies path to a timestamped file next to it and returns its
+// name, nothing is copied when path doesn't exist yet
+func backupFile(path string) (string, error) {
+	src, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	backup := backupPath(path, time.Now())
+	dst, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return "", err
+	}
+
+	return backup, dst.Close()
+}
+
+// saveWorkbook writes f to path, backing up the workbook already there
+// unless --no-backup is given, and returns the backup's name
+func saveWorkbook(f *excelize.File, path string) (string, error) {
+	var backup string
+	if !noBackupf {
+		var err error
+		if backup, err = backupFile(path); err != nil {
+			return "", err
+		}
+		if backup != "" {
+			printer.Fprintf(out, "Backed up %s to %s\n", path, backup)
+		}
+	}
+
+	return backup, f.SaveAs(path)
+}
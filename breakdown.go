@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// wordTally is the work of one author or sheet
+type wordTally struct {
+	name             string
+	entries          int
+	translationWords int
+	checkWords       int
+	notInvoiced      int
+}
+
+func (t *wordTally) add(e Entry) {
+	words, _ := strconv.Atoi(strings.ReplaceAll(e.WordCount(), ",", ""))
+
+	t.entries++
+	switch e.Type() {
+	case "翻訳":
+		t.translationWords += words
+	case "英文チェック":
+		t.checkWords += words
+	}
+}
+
+// tallyShuho sums the scoped shuho entries by key, in the order the keys first
+// appear. Entries the shuho-in-invoice check reports count as not invoiced.
+func tallyShuho(shuho, invoice []Entry, key func(ShuhoEntry) string) []*wordTally {
+	missing := make(map[Location]bool)
+	for _, finding := range ensureShuhoEntriesAreInInvoice(shuho, invoice) {
+		missing[finding.Location()] = true
+	}
+
+	byKey := make(map[string]*wordTally)
+	var tallies []*wordTally
+	for _, entry := range getScopedShuho(shuho, invoice) {
+		se, ok := entry.(ShuhoEntry)
+		if !ok {
+			continue
+		}
+		t, ok := byKey[key(se)]
+		if !ok {
+			t = &wordTally{name: key(se)}
+			byKey[key(se)] = t
+			tallies = append(tallies, t)
+		}
+		t.add(se)
+		if missing[se.Location()] {
+			t.notInvoiced++
+		}
+	}
+
+	return tallies
+}
+
+// printTallies prints one row per tally and their total, aligned in columns
+func printTallies(title, column string, tallies []*wordTally) {
+	colorize(ColorGreen, translate(title))
+
+	total := wordTally{name: translate("Total")}
+	for _, t := range tallies {
+		total.entries += t.entries
+		total.translationWords += t.translationWords
+		total.checkWords += t.checkWords
+		total.notInvoiced += t.notInvoiced
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", translate(column), translate("Entries"), translate("Translation words"), translate("Check words"), translate("Not invoiced"))
+	for _, t := range append(tallies, &total) {
+		printer.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", t.name, t.entries, t.translationWords, t.checkWords, t.notInvoiced)
+	}
+	w.Flush()
+}
+
+// printAuthorBreakdown sums the scoped shuho entries per author, for shuhos shared by a team
+func printAuthorBreakdown(shuho, invoice []Entry) {
+	tallies := tallyShuho(shuho, invoice, func(se ShuhoEntry) string { return se.SAuthor })
+	sort.Slice(tallies, func(i, j int) bool { return tallies[i].name < tallies[j].name })
+
+	printTallies("\n** Per author: ", "Author", tallies)
+}
+
+// printSheetSubtotals sums the scoped shuho entries per sheet, in workbook
+// order, to find the week missing work came from when the totals don't add up
+func printSheetSubtotals(shuho, invoice []Entry) {
+	tallies := tallyShuho(shuho, invoice, func(se ShuhoEntry) string { return se.Location().Sheet })
+
+	printTallies("\n** Per shuho sheet: ", "Sheet", tallies)
+}
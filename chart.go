@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// chartPeriod is the --chart flag, the invoiced words per day or per week
+type chartPeriod string
+
+func (c *chartPeriod) String() string {
+	return string(*c)
+}
+
+func (c *chartPeriod) Set(value string) error {
+	if value != "day" && value != "week" {
+		return fmt.Errorf("unknown chart %q, use day or week", value)
+	}
+	*c = chartPeriod(value)
+
+	return nil
+}
+
+var chartf chartPeriod
+
+// chartWidth is the length of the longest bar
+const chartWidth = 40
+
+// printWorkloadChart draws the invoiced words of every day or week of the
+// period as a bar of # for translations and + for checks. Days without work
+// are drawn too, the gaps are the point of the chart.
+func printWorkloadChart(invoice []Entry, period chartPeriod) {
+	type bucket struct {
+		start                time.Time
+		translations, checks int
+	}
+
+	first, last := invoice[0].Date(), invoice[0].Date()
+	for _, e := range invoice {
+		if e.Date().Before(first) {
+			first = e.Date()
+		}
+		if e.Date().After(last) {
+			last = e.Date()
+		}
+	}
+
+	bucketStart := func(date time.Time) time.Time {
+		if period == "week" {
+			//weeks start on Monday
+			return date.AddDate(0, 0, -(int(date.Weekday())+6)%7)
+		}
+		return date
+	}
+	step := 1
+	if period == "week" {
+		step = 7
+	}
+
+	var buckets []*bucket
+	byStart := make(map[time.Time]*bucket)
+	for day := bucketStart(first); !day.After(last); day = day.AddDate(0, 0, step) {
+		b := &bucket{start: day}
+		buckets = append(buckets, b)
+		byStart[day] = b
+	}
+
+	var most int
+	for _, e := range invoice {
+		b := byStart[bucketStart(e.Date())]
+		words, _ := strconv.Atoi(strings.ReplaceAll(e.WordCount(), ",", ""))
+		switch e.Type() {
+		case "翻訳":
+			b.translations += words
+		case "英文チェック":
+			b.checks += words
+		}
+		if b.translations+b.checks > most {
+			most = b.translations + b.checks
+		}
+	}
+
+	if period == "week" {
+		colorize(ColorGreen, translate("\n** Words per week: "))
+	} else {
+		colorize(ColorGreen, translate("\n** Words per day: "))
+	}
+	for _, b := range buckets {
+		translations, checks := scaleBar(b.translations, most), scaleBar(b.checks, most)
+		printer.Fprintf(out, "%s %s |%s%s %d\n", b.start.Format("01-02"), translate(b.start.Weekday().String()[:3]), strings.Repeat("#", translations), strings.Repeat("+", checks), b.translations+b.checks)
+	}
+	printer.Fprintf(out, "# translation words, + check words\n")
+}
+
+// scaleBar is the length of the bar for words when most words fill chartWidth,
+// any work at all gets at least one character
+func scaleBar(words, most int) int {
+	if words <= 0 || most <= 0 {
+		return 0
+	}
+	if n := words * chartWidth / most; n > 0 {
+		return n
+	}
+
+	return 1
+}
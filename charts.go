@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// --charts writes these as svg or png files into a directory
+var chartsf string
+var chartFormatf = "svg"
+
+// barChart is a stacked bar chart, series[i][j] is the part of bar j in series i
+type barChart struct {
+	name   string
+	title  string
+	labels []string
+	names  []string
+	series [][]float64
+}
+
+var chartColors = []color.RGBA{{0x2b, 0x6c, 0xb0, 0xff}, {0xe0, 0x8a, 0x1e, 0xff}}
+
+// historyCharts are the charts of the runs in records, the latest run of every
+// month for the monthly ones and the words per day of the last run
+func historyCharts(records []HistoryRecord) []barChart {
+	months := latestByMonth(records)
+
+	income := barChart{name: "monthly-income", title: translate("Pre-tax total per month"), names: []string{translate("Pre-tax total")}, series: make([][]float64, 1)}
+	split := barChart{name: "translation-check-split", title: translate("Translations and checks per month"), names: []string{translate("Translations"), translate("Checks")}, series: make([][]float64, 2)}
+	for _, record := range months {
+		income.labels = append(income.labels, record.Month)
+		income.series[0] = append(income.series[0], record.Pretax)
+		split.labels = append(split.labels, record.Month)
+		split.series[0] = append(split.series[0], record.Translations)
+		split.series[1] = append(split.series[1], record.Checks)
+	}
+
+	words := barChart{name: "words-per-day", title: translate("Invoiced words per day"), names: []string{translate("Translation words"), translate("Check words")}, series: make([][]float64, 2)}
+	if len(records) > 0 {
+		last := records[len(records)-1].Entries
+		seen := make(map[string]bool)
+		for _, e := range last {
+			if !seen[e.Date] {
+				seen[e.Date] = true
+				words.labels = append(words.labels, e.Date)
+			}
+		}
+		sort.Strings(words.labels)
+		words.series[0] = make([]float64, len(words.labels))
+		words.series[1] = make([]float64, len(words.labels))
+		for _, e := range last {
+			i := sort.SearchStrings(words.labels, e.Date)
+			n, _ := strconv.Atoi(strings.ReplaceAll(e.Words, ",", ""))
+			switch e.Type {
+			case "翻訳":
+				words.series[0][i] += float64(n)
+			case "英文チェック":
+				words.series[1][i] += float64(n)
+			}
+		}
+		//the days are all in the invoiced period, 2023-06-20 is labelled 06-20
+		for i, label := range words.labels {
+			if len(label) > 5 {
+				words.labels[i] = label[5:]
+			}
+		}
+	}
+
+	return []barChart{income, split, words}
+}
+
+// writeCharts writes every chart into dir as format, svg or png
+func writeCharts(dir, format string, charts []barChart) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, chart := range charts {
+		f, err := os.Create(filepath.Join(dir, chart.name+"."+format))
+		if err != nil {
+			return err
+		}
+		w := bufio.NewWriter(f)
+		if format == "png" {
+			err = writePNGChart(w, chart)
+		} else {
+			err = writeSVGChart(w, chart)
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+const (
+	chartImageWidth  = 720
+	chartImageHeight = 360
+	chartMargin      = 48
+)
+
+// tallest is the largest stacked bar, the height every bar is scaled to
+func (c barChart) tallest() float64 {
+	var tallest float64
+	for j := range c.labels {
+		var sum float64
+		for _, s := range c.series {
+			sum += s[j]
+		}
+		if sum > tallest {
+			tallest = sum
+		}
+	}
+
+	return tallest
+}
+
+// bars calls draw with the pixel rectangle of every part of every bar
+func (c barChart) bars(draw func(series, bar int, x, y, width, height int)) {
+	tallest := c.tallest()
+	if len(c.labels) == 0 || tallest == 0 {
+		return
+	}
+
+	slot := (chartImageWidth - 2*chartMargin) / len(c.labels)
+	plot := chartImageHeight - 2*chartMargin
+	for j := range c.labels {
+		bottom := chartImageHeight - chartMargin
+		for i, s := range c.series {
+			height := int(s[j] / tallest * float64(plot))
+			draw(i, j, chartMargin+j*slot+slot/8, bottom-height, slot*3/4, height)
+			bottom -= height
+		}
+	}
+}
+
+func writeSVGChart(w io.Writer, c barChart) error {
+	slot := 0
+	if len(c.labels) > 0 {
+		slot = (chartImageWidth - 2*chartMargin) / len(c.labels)
+	}
+
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="11">`+"\n", chartImageWidth, chartImageHeight)
+	fmt.Fprintf(w, `<text x="%d" y="24" font-size="15">%s</text>`+"\n", chartMargin, html.EscapeString(c.title))
+	c.bars(func(series, bar, x, y, width, height int) {
+		col := chartColors[series%len(chartColors)]
+		fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" fill="#%02x%02x%02x"><title>%s %s: %s</title></rect>`+"\n", x, y, width, height, col.R, col.G, col.B, html.EscapeString(c.labels[bar]), html.EscapeString(c.names[series]), printer.Sprintf("%.0f", c.series[series][bar]))
+	})
+	for j, label := range c.labels {
+		fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n", chartMargin+j*slot+slot/2, chartImageHeight-chartMargin+16, html.EscapeString(label))
+	}
+	fmt.Fprintf(w, `<text x="%d" y="%d">%s</text>`+"\n", chartMargin, chartImageHeight-chartMargin+36, html.EscapeString(printer.Sprintf("max %.0f", c.tallest())))
+	for i, name := range c.names {
+		col := chartColors[i%len(chartColors)]
+		fmt.Fprintf(w, `<rect x="%d" y="%d" width="10" height="10" fill="#%02x%02x%02x"/><text x="%d" y="%d">%s</text>`+"\n", chartImageWidth-chartMargin-160, 14+i*16, col.R, col.G, col.B, chartImageWidth-chartMargin-144, 23+i*16, html.EscapeString(name))
+	}
+	_, err := fmt.Fprintln(w, "</svg>")
+
+	return err
+}
+
+// writePNGChart draws the bars only, the standard library has no fonts for the labels
+func writePNGChart(w io.Writer, c barChart) error {
+	img := image.NewRGBA(image.Rect(0, 0, chartImageWidth, chartImageHeight))
+	fill := func(r image.Rectangle, col color.Color) {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				img.Set(x, y, col)
+			}
+		}
+	}
+
+	fill(img.Bounds(), color.White)
+	fill(image.Rect(chartMargin, chartImageHeight-chartMargin, chartImageWidth-chartMargin, chartImageHeight-chartMargin+1), color.Black)
+	c.bars(func(series, bar, x, y, width, height int) {
+		fill(image.Rect(x, y, x+width, y+height), chartColors[series%len(chartColors)])
+	})
+
+	return png.Encode(w, img)
+}
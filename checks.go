@@ -0,0 +1,187 @@
+package main
+
+import "time"
+
+// Check is one verification over the parsed entries. Name is the check id
+// used by the severities config, the ignore file and the reports.
+//
+// Company specific checks can be compiled in from their own file:
+//
+//	func init() {
+//		RegisterCheck(poNumberCheck{})
+//	}
+type Check interface {
+	Name() string
+	Run(shuho, invoice []Entry) []Finding
+}
+
+// a Check can also describe itself, the description is printed when it passes
+type describedCheck interface {
+	Description() string
+}
+
+var checks []Check
+
+// RegisterCheck adds c to the checks run on every verification, in registration order
+func RegisterCheck(c Check) {
+	checks = append(checks, c)
+}
+
+// checkFunc adapts a plain function to the Check interface
+type checkFunc struct {
+	name        string
+	description string
+	run         func(shuho, invoice []Entry) []Finding
+}
+
+func (c checkFunc) Name() string {
+	return c.name
+}
+
+func (c checkFunc) Description() string {
+	return c.description
+}
+
+func (c checkFunc) Run(shuho, invoice []Entry) []Finding {
+	return c.run(shuho, invoice)
+}
+
+func init() {
+	RegisterCheck(checkFunc{"rates", "Invoice rates are correct", func(shuho, invoice []Entry) []Finding {
+		return ensureRatesAreCorrect(invoice)
+	}})
+	RegisterCheck(checkFunc{"rate-table", "Invoice rates are in the rate table", func(shuho, invoice []Entry) []Finding {
+		return ensureRatesInTable(invoice, rateTable())
+	}})
+	RegisterCheck(checkFunc{"duplicates", "No Duplicate Invoice Entries", func(shuho, invoice []Entry) []Finding {
+		return ensureNoDuplicateInvoiceEntries(invoice)
+	}})
+	RegisterCheck(checkFunc{"invoice-in-shuho", "All Invoice Entries are in the Shuho", ensureInvoiceEntriesAreInShuho})
+	RegisterCheck(checkFunc{"shuho-in-invoice", "All Shuho Entries are in the Invoice", ensureShuhoEntriesAreInInvoice})
+	RegisterCheck(checkFunc{"case-totals", "Word counts per case and type match", ensureCaseTotalsMatch})
+	RegisterCheck(checkFunc{"cancelled", "No cancelled entries on the invoice", func(shuho, invoice []Entry) []Finding {
+		return ensureCancelledNotInvoiced(cancelledEntries, shuho, invoice)
+	}})
+	RegisterCheck(checkFunc{"both-wordcounts", "No Shuho rows with both word counts", ensureSingleWordCount})
+	RegisterCheck(checkFunc{"word-counts", "All word counts are positive numbers", ensureValidWordCounts})
+	RegisterCheck(checkFunc{"unknown-type", "All entries have a known type", ensureKnownTypes})
+	RegisterCheck(checkFunc{"authors", "All shuho authors are in the authors list", func(shuho, invoice []Entry) []Finding {
+		return ensureKnownAuthors(shuho, invoice, config.Authors)
+	}})
+	RegisterCheck(checkFunc{"chronological", "Invoice entries are in chronological order", func(shuho, invoice []Entry) []Finding {
+		return ensureInvoiceIsChronological(invoice)
+	}})
+	RegisterCheck(checkFunc{"row-numbers", "Invoice rows are numbered 1 to N", func(shuho, invoice []Entry) []Finding {
+		return ensureContinuousRowNumbers(invoice)
+	}})
+	RegisterCheck(checkFunc{"invoice-header", "Invoice header matches the invoiced month", func(shuho, invoice []Entry) []Finding {
+		return checkInvoiceHeader(parsedInvoiceHeader, config.InvoiceHeader, invoice)
+	}})
+	RegisterCheck(checkFunc{"invoice-number", "Invoice number follows the last recorded invoice", func(shuho, invoice []Entry) []Finding {
+		first, _, ok := invoicePeriod(invoice)
+		if !ok {
+			return nil
+		}
+		return checkInvoiceNumber(parsedInvoiceHeader, first.Format("2006-01"), historyOf(history, shuho))
+	}})
+	RegisterCheck(checkFunc{"invoiced-before", "No entries invoiced in another month", func(shuho, invoice []Entry) []Finding {
+		return checkInvoicedBefore(invoice, historyOf(history, shuho))
+	}})
+	RegisterCheck(checkFunc{"overdue", "No shuho entries overdue for invoicing", func(shuho, invoice []Entry) []Finding {
+		return ensureNothingOverdue(shuho, invoice, historyOf(history, shuho))
+	}})
+	RegisterCheck(checkFunc{"word-outliers", "No word counts far outside the history", func(shuho, invoice []Entry) []Finding {
+		return checkWordCountOutliers(invoice, historyOf(history, shuho))
+	}})
+	RegisterCheck(checkFunc{"daily-capacity", "No shuho day over the daily capacity", func(shuho, invoice []Entry) []Finding {
+		return ensureDailyCapacity(shuho, invoice, config.DailyCapacity)
+	}})
+	RegisterCheck(checkFunc{"tracked-time", "No shuho day without tracked time", func(shuho, invoice []Entry) []Finding {
+		return ensureTimeTracked(shuho, invoice, trackedHours)
+	}})
+	RegisterCheck(checkFunc{"days-off", "No shuho entries on a day off", checkDaysOff})
+}
+
+// excludeCases leaves out the entries of the excluded_cases config
+func excludeCases(entries []Entry, cases []string) []Entry {
+	if len(cases) == 0 {
+		return entries
+	}
+	excluded := make(map[string]bool)
+	for _, c := range cases {
+		excluded[c] = true
+	}
+
+	kept := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if !excluded[e.CaseNum()] {
+			kept = append(kept, e)
+		}
+	}
+
+	return kept
+}
+
+// matchCase is the case number entries are matched by, a case renamed
+// midway is matched by the number of the case_aliases config
+func matchCase(caseNum string) string {
+	if earlier, ok := config.CaseAliases[caseNum]; ok {
+		return earlier
+	}
+
+	return caseNum
+}
+
+// checkPeriod is the first and last date of the invoice being checked,
+// its excluded cases included, so an invoice of nothing but excluded cases
+// still scopes the shuho to its month
+var checkPeriod struct {
+	start, end time.Time
+}
+
+// invoicePeriod is the first and last date of the invoice, checkPeriod
+// while runChecks runs and the dates of ientries otherwise
+func invoicePeriod(ientries []Entry) (time.Time, time.Time, bool) {
+	if !checkPeriod.start.IsZero() {
+		return checkPeriod.start, checkPeriod.end, true
+	}
+	if len(ientries) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return ientries[0].Date(), ientries[len(ientries)-1].Date(), true
+}
+
+func runChecks(shuhoEntries []Entry, invoiceEntries []Entry) []CheckResult {
+	start := time.Now()
+	results := make([]CheckResult, 0, len(checks)+len(ruleChecks)+1)
+	if len(invoiceEntries) > 0 {
+		checkPeriod.start, checkPeriod.end = invoiceEntries[0].Date(), invoiceEntries[len(invoiceEntries)-1].Date()
+		defer func() { checkPeriod.start, checkPeriod.end = time.Time{}, time.Time{} }()
+	}
+	shuhoEntries = excludeCases(shuhoEntries, config.ExcludedCases)
+	invoiceEntries = excludeCases(invoiceEntries, config.ExcludedCases)
+
+	for _, c := range append(checks[:len(checks):len(checks)], ruleChecks...) {
+		if !checkEnabled(c.Name()) {
+			continue
+		}
+		result := CheckResult{ID: c.Name(), Name: c.Name(), Findings: c.Run(shuhoEntries, invoiceEntries)}
+		if d, ok := c.(describedCheck); ok {
+			result.Name = d.Description()
+		}
+		results = append(results, result)
+	}
+
+	if strictParsef {
+		results = append(results, checkParseIssues(parseIssues))
+	}
+
+	applySeverities(results, config.Severities)
+
+	stats.Ignored = filterIgnored(results, ignoreRules)
+	stats.addResults(results)
+	stats.CheckSeconds = time.Since(start).Seconds()
+
+	return results
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// columnMapping says which spreadsheet column holds each field, the parsers
+// read rows rearranged into the field order of the standard template
+type columnMapping struct {
+	fields []string
+	cols   []int
+	// given on the command line, which turns off the invoice layout detection
+	set bool
+}
+
+func newColumnMapping(fields ...string) *columnMapping {
+	m := &columnMapping{fields: fields, cols: make([]int, len(fields))}
+	for i := range m.cols {
+		m.cols[i] = i
+	}
+
+	return m
+}
+
+// optional adds fields that aren't in the standard template, they are
+// only read once mapped to a column
+func (m *columnMapping) optional(fields ...string) *columnMapping {
+	for _, field := range fields {
+		m.fields = append(m.fields, field)
+		m.cols = append(m.cols, -1)
+	}
+
+	return m
+}
+
+// mapped is false for optional fields without a column
+func (m *columnMapping) mapped(name string) bool {
+	i := m.field(name)
+
+	return i >= 0 && m.cols[i] >= 0
+}
+
+func (m *columnMapping) String() string {
+	if m == nil {
+		return ""
+	}
+
+	var pairs []string
+	for i, field := range m.fields {
+		if m.cols[i] < 0 {
+			continue
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%s", field, columnLetters(m.cols[i])))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// Set takes field=column pairs such as date=D,case=B, columns are letters or
+// 1-based numbers and - leaves a field out
+func (m *columnMapping) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		field, column, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return fmt.Errorf("expected field=column, got %q", pair)
+		}
+
+		i := m.field(strings.TrimSpace(field))
+		if i < 0 {
+			return fmt.Errorf("unknown field %q, use %s", field, strings.Join(m.fields, ", "))
+		}
+		//- for a field the workbook doesn't have, such as the row numbers
+		if strings.TrimSpace(column) == "-" {
+			m.cols[i] = -1
+			continue
+		}
+		col, err := parseColumn(strings.TrimSpace(column))
+		if err != nil {
+			return err
+		}
+		m.cols[i] = col
+	}
+	m.set = true
+
+	return nil
+}
+
+func (m *columnMapping) field(name string) int {
+	for i, field := range m.fields {
+		if field == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// apply picks the mapped cells out of row, in field order
+func (m *columnMapping) apply(row []string) []string {
+	mapped := make([]string, len(m.fields))
+	for i, col := range m.cols {
+		if col >= 0 && col < len(row) {
+			mapped[i] = row[col]
+		}
+	}
+
+	return mapped
+}
+
+// parseColumn turns D or 4 into the zero based column index 3
+func parseColumn(column string) (int, error) {
+	if n, err := strconv.Atoi(column); err == nil {
+		if n < 1 {
+			return 0, fmt.Errorf("column %d: columns start at 1", n)
+		}
+		return n - 1, nil
+	}
+
+	var n int
+	for _, r := range strings.ToUpper(column) {
+		if r < 'A' || r > 'Z' {
+			return 0, fmt.Errorf("bad column %q", column)
+		}
+		n = n*26 + int(r-'A'+1)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("bad column %q", column)
+	}
+
+	return n - 1, nil
+}
+
+func columnLetters(col int) string {
+	var letters string
+	for col++; col > 0; col = (col - 1) / 26 {
+		letters = string(rune('A'+(col-1)%26)) + letters
+	}
+
+	return letters
+}
+
+// the standard templates: invoice No., case, type, date, words, rate and
+// shuho date, case, type, check words, translation words, note, author,
+// with the PO number where the agency asks for one and the invoice client
+// where one invoice bills several
+var invoiceColumnsf = newColumnMapping("no", "case", "type", "date", "words", "rate").optional("po", "client")
+var shuhoColumnsf = newColumnMapping("date", "case", "type", "check", "translation", "note", "author").optional("po")
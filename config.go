@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// Config is read from --config, or verifyshuho/config.json in the user config directory
+type Config struct {
+	// check id -> error, warning or info, overriding the check's own severities
+	Severities map[string]string `json:"severities"`
+	// accepted discrepancies, see IgnoreRule
+	IgnoreFile string `json:"ignore_file"`
+	// declarative per-entry checks, see Rule
+	Rules []Rule `json:"rules"`
+	// which rows hold entries, see RowPatterns
+	Patterns RowPatterns `json:"patterns"`
+	// runs are appended to this file, see HistoryRecord
+	HistoryFile string `json:"history_file"`
+	// the /YR figure after the pre-tax total, see Projection
+	Projection Projection `json:"projection"`
+	// what the invoice header has to say, see HeaderExpectations
+	InvoiceHeader HeaderExpectations `json:"invoice_header"`
+	// case numbers left out of every check, cancelled or internal cases
+	ExcludedCases []string `json:"excluded_cases"`
+	// renamed case -> its earlier number, matched as the same case
+	CaseAliases map[string]string `json:"case_aliases"`
+	// type -> rate, replacing the 翻訳 18 and 英文チェック 1.4 of invoiceRates
+	Rates map[string]string `json:"rates"`
+	// rates that change on a date, see RatePeriod
+	RatePeriods []RatePeriod `json:"rate_periods"`
+	// flat amounts for small jobs, see RateTier
+	RateTiers []RateTier `json:"rate_tiers"`
+	// client -> its rates, see ClientProfile
+	Clients map[string]ClientProfile `json:"clients"`
+	// how amounts are rounded to the yen, see Rounding
+	Rounding Rounding `json:"rounding"`
+	// type -> the most words of it a day can plausibly hold, see the daily-capacity check
+	DailyCapacity map[string]int `json:"daily_capacity"`
+	// the type labels of older or English sheets, see TypeLabels
+	TypeLabels []TypeLabels `json:"type_labels"`
+	// the column layouts of older shuho sheets, see SheetLayout
+	ShuhoLayouts []SheetLayout `json:"shuho_layouts"`
+	// layout schema files of workbooks without a name.schema.json, see LayoutSchema
+	Schemas Schemas `json:"schemas"`
+	// every shuho author, the authors check reports any other name
+	Authors []string `json:"authors"`
+	// the user's own days off besides the weekends and public holidays, see loadHolidayFile
+	HolidaysFile string `json:"holidays_file"`
+}
+
+// Projection is how the yearly figure is worked out from the pre-tax total:
+//
+//	"projection": {"months": 11, "year_to_date": true}
+//
+// year_to_date projects the average month of the year so far in the history
+// file instead of this month alone, disabled leaves the figure out.
+type Projection struct {
+	// months worked in a year, 12 when not set
+	Months     int  `json:"months"`
+	YearToDate bool `json:"year_to_date"`
+	Disabled   bool `json:"disabled"`
+}
+
+// RowPatterns are the regular expressions deciding which rows are entries,
+// empty ones keep the defaults. A row is an invoice entry when its date cell
+// matches InvoiceDate, a shuho entry when its date cell matches ShuhoDate, and
+// a row whose case number matches EmptyCase is an unfilled template row.
+//
+//	"patterns": {"invoice_date": "^\\d{4}/\\d+/\\d+$", "empty_case": "^(?i)(ALP|BET)-$"}
+type RowPatterns struct {
+	InvoiceDate string `json:"invoice_date"`
+	ShuhoDate   string `json:"shuho_date"`
+	EmptyCase   string `json:"empty_case"`
+}
+
+// invoice dates are mm-dd-yy, shuho dates m/d
+var (
+	invoiceDateRe  = regexp.MustCompile(`\d+-\d+-\d+$`)
+	shuhoRowDateRe = regexp.MustCompile(`^(?i)\d+/\d+$`)
+	emptyCaseRe    = regexp.MustCompile(`^(?i)ALP-$`)
+)
+
+// a cancelled shuho row has an x before its date, x6/5
+var cancelledDateRe = regexp.MustCompile(`^[xX]\s*\S`)
+
+// setRowPatterns replaces the default patterns with the configured ones
+func setRowPatterns(p RowPatterns) error {
+	for _, pattern := range []struct {
+		name, expr string
+		re         **regexp.Regexp
+	}{
+		{"invoice_date", p.InvoiceDate, &invoiceDateRe},
+		{"shuho_date", p.ShuhoDate, &shuhoRowDateRe},
+		{"empty_case", p.EmptyCase, &emptyCaseRe},
+	} {
+		if pattern.expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern.expr)
+		if err != nil {
+			return fmt.Errorf("pattern %s: %w", pattern.name, err)
+		}
+		*pattern.re = re
+	}
+
+	return nil
+}
+
+var configf string
+var config Config
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "verifyshuho", "config.json")
+}
+
+// loadConfig reads path, a missing file at the default location is not an error
+func loadConfig(path string) (Config, error) {
+	var c Config
+
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, id := range sortedKeys(c.Severities) {
+		name := c.Severities[id]
+		if _, ok := parseSeverity(name); !ok {
+			return c, fmt.Errorf("%s: unknown severity %q for check %s", path, name, id)
+		}
+	}
+
+	if c.Projection.Months < 0 || c.Projection.Months > 12 {
+		return c, fmt.Errorf("%s: projection months must be between 1 and 12, got %d", path, c.Projection.Months)
+	}
+
+	if _, err := regexp.Compile(c.InvoiceHeader.NumberPattern); err != nil {
+		return c, fmt.Errorf("%s: invoice_header number_pattern: %w", path, err)
+	}
+	for _, eType := range sortedKeys(c.Rates) {
+		rate := c.Rates[eType]
+		if _, err := strconv.ParseFloat(rate, 64); err != nil {
+			return c, fmt.Errorf("%s: rate %q of %s isn't a number", path, rate, eType)
+		}
+	}
+
+	if mode := c.Rounding.Mode; mode != "" {
+		known := false
+		for _, m := range roundingModes {
+			known = known || m == mode
+		}
+		if !known {
+			return c, fmt.Errorf("%s: unknown rounding mode %q, use half-up, half-even or floor", path, mode)
+		}
+	}
+
+	for _, client := range sortedKeys(c.Clients) {
+		for _, eType := range sortedKeys(c.Clients[client].Rates) {
+			rate := c.Clients[client].Rates[eType]
+			if _, err := strconv.ParseFloat(rate, 64); err != nil {
+				return c, fmt.Errorf("%s: rate %q of %s for %s isn't a number", path, rate, eType, client)
+			}
+		}
+	}
+
+	for _, set := range c.TypeLabels {
+		if _, err := regexp.Compile(set.Sheets); err != nil {
+			return c, fmt.Errorf("%s: type_labels sheets: %w", path, err)
+		}
+	}
+
+	if err := checkLayouts(c.ShuhoLayouts); err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, field := range c.InvoiceHeader.Required {
+		if _, ok := headerLabels[field]; !ok {
+			return c, fmt.Errorf("%s: unknown invoice_header field %q, use translator, number, period or issue_date", path, field)
+		}
+	}
+
+	return c, nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// daemonRequest is one line sent to the daemon socket:
+//
+//	{"op": "verify", "shuho": "/abs/shuho.xlsx", "invoice": "/abs/invoice.xlsx"}
+//	{"op": "case", "shuho": "/abs/shuho.xlsx", "case": "ALP-1234"}
+//
+// every request is answered with one daemonResponse line
+type daemonRequest struct {
+	Op      string `json:"op"`
+	Shuho   string `json:"shuho"`
+	Invoice string `json:"invoice,omitempty"`
+	Case    string `json:"case,omitempty"`
+}
+
+type daemonResponse struct {
+	Status  int             `json:"status"`
+	Report  string          `json:"report,omitempty"`
+	Entries []exportedEntry `json:"entries,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+func defaultSocketPath() string {
+	return filepath.Join(os.TempDir(), "verifyshuho.sock")
+}
+
+// cachedWorkbook keeps the rows of a workbook read once, reading the cells is
+// most of the time a verification takes
+type cachedWorkbook struct {
+	Workbook
+	modTime time.Time
+	size    int64
+	input   inputFile
+	rows    map[string][][]string
+}
+
+func (w *cachedWorkbook) SheetRows(sheet string) ([][]string, error) {
+	if rows, ok := w.rows[sheet]; ok {
+		return rows, nil
+	}
+	rows, err := w.Workbook.SheetRows(sheet)
+	if err == nil {
+		w.rows[sheet] = rows
+	}
+
+	return rows, err
+}
+
+// the cached workbooks stay open, they are closed when the file changes
+func (w *cachedWorkbook) Close() error {
+	return nil
+}
+
+// workbookCache holds the workbooks by path until their file changes
+type workbookCache struct {
+	workbooks map[string]*cachedWorkbook
+}
+
+func (c *workbookCache) open(path string) (*cachedWorkbook, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if w, ok := c.workbooks[path]; ok {
+		if w.modTime.Equal(info.ModTime()) && w.size == info.Size() {
+			return w, nil
+		}
+		w.Workbook.Close()
+		delete(c.workbooks, path)
+	}
+
+	f, err := openWorkbook(path)
+	if err != nil {
+		return nil, err
+	}
+	input, err := newInputFile(path)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w := &cachedWorkbook{Workbook: f, modTime: info.ModTime(), size: info.Size(), input: input, rows: make(map[string][][]string)}
+	c.workbooks[path] = w
+
+	return w, nil
+}
+
+// daemonCommand answers verification and case queries on a unix socket,
+// keeping the workbooks it read in memory for the next request. The config,
+// rules, history and ignore file are read once at startup, the schemas of
+// the workbooks with every request.
+func daemonCommand(args []string) int {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	socket := fs.String("socket", defaultSocketPath(), "unix socket to listen on")
+	checkFlags(fs)
+	fs.Parse(args)
+
+	if deterministicf {
+		now = func() time.Time { return deterministicNow }
+	}
+	if err := loadSettings(); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	if err := removeStaleSocket(*socket); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	l, err := net.Listen("unix", *socket)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	defer l.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		l.Close()
+	}()
+
+	printer.Fprintf(out, "Listening on %s\n", *socket)
+
+	cache := &workbookCache{workbooks: make(map[string]*cachedWorkbook)}
+	cache.serve(l)
+
+	return 0
+}
+
+// removeStaleSocket removes the socket left at path by a daemon that was
+// killed. Anything else there, a file or a daemon still answering, is an error.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and isn't a socket", path)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("a daemon is already listening on %s", path)
+	}
+
+	return os.Remove(path)
+}
+
+// serve answers the requests of every connection to l until it's closed
+func (c *workbookCache) serve(l net.Listener) {
+	//the verification works on package state, one request at a time
+	var mu sync.Mutex
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			scanner := bufio.NewScanner(conn)
+			enc := json.NewEncoder(conn)
+			for scanner.Scan() {
+				var req daemonRequest
+				var resp daemonResponse
+				if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+					resp = daemonResponse{Status: 2, Error: err.Error()}
+				} else {
+					mu.Lock()
+					resp = c.handle(req)
+					mu.Unlock()
+				}
+				if enc.Encode(resp) != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (c *workbookCache) handle(req daemonRequest) daemonResponse {
+	fshuho, err := c.open(req.Shuho)
+	if err != nil {
+		return daemonResponse{Status: 2, Error: err.Error()}
+	}
+
+	switch req.Op {
+	case "verify":
+		finvoice, err := c.open(req.Invoice)
+		if err != nil {
+			return daemonResponse{Status: 2, Error: err.Error()}
+		}
+		report, status := verifyToText(fshuho, finvoice, fshuho.input, finvoice.input)
+		return daemonResponse{Status: status, Report: report}
+	case "case":
+		parseIssues, cancelledEntries = nil, nil
+		shuhoPeriod.start, shuhoPeriod.end = time.Time{}, time.Time{}
+		entries, err := parseShuho(fshuho)
+		resp := daemonResponse{Entries: []exportedEntry{}}
+		for _, e := range entries {
+			if e.CaseNum() == req.Case {
+				resp.Entries = append(resp.Entries, newExportedEntry(e))
+			}
+		}
+		if err != nil {
+			resp.Status, resp.Error = 1, err.Error()
+		}
+		return resp
+	}
+
+	return daemonResponse{Status: 2, Error: fmt.Sprintf("unknown op %q, use verify or case", req.Op)}
+}
+
+// verifyToText runs verifyWorkbooks with the report going to a plain text
+// string, under the schemas of these workbooks only
+func verifyToText(fshuho, finvoice Workbook, shuhoInput, invoiceInput inputFile) (string, int) {
+	var report bytes.Buffer
+	defer func(w io.Writer) { out = w }(out)
+	out = plainWriter{&report}
+
+	defer keepSchemaState()()
+	if err := loadSchemas(shuhoInput.Path, invoiceInput.Path); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return report.String(), 2
+	}
+
+	status := verifyWorkbooks(fshuho, finvoice, shuhoInput, invoiceInput)
+
+	return report.String(), status
+}
+
+// askCommand sends a request to the daemon and prints the answer:
+//
+//	verifyshuho ask verify <shuho.xlsx> <invoice.xlsx>
+//	verifyshuho ask case <shuho.xlsx> <case number>
+func askCommand(args []string) int {
+	fs := flag.NewFlagSet("ask", flag.ExitOnError)
+	socket := fs.String("socket", defaultSocketPath(), "unix socket of the daemon")
+	fs.Parse(args)
+
+	if fs.NArg() != 3 || (fs.Arg(0) != "verify" && fs.Arg(0) != "case") {
+		fmt.Fprintln(out, "\033[1;31mERROR Usage:\033[0m ./verifyshuho ask [--socket path] verify <shuho.xlsx> <invoice.xlsx> | case <shuho.xlsx> <case number>")
+		return 2
+	}
+
+	//the daemon has its own working directory
+	req := daemonRequest{Op: fs.Arg(0)}
+	req.Shuho, _ = filepath.Abs(fs.Arg(1))
+	if req.Op == "verify" {
+		req.Invoice, _ = filepath.Abs(fs.Arg(2))
+	} else {
+		req.Case = fs.Arg(2)
+	}
+
+	conn, err := net.Dial("unix", *socket)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	defer conn.Close()
+
+	var resp daemonResponse
+	err = json.NewEncoder(conn).Encode(req)
+	if err == nil {
+		err = json.NewDecoder(conn).Decode(&resp)
+	}
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	fmt.Fprint(out, resp.Report)
+	for _, e := range resp.Entries {
+		fmt.Fprintf(out, "%s [%s] row %d: %s %s %s %s %s\n", e.File, e.Sheet, e.Row, e.Date, e.Case, typeLabel(e.Type), e.Words, e.Author)
+	}
+	if resp.Error != "" {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", resp.Error)
+	}
+
+	return resp.Status
+}
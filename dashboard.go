@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// dashboardCommand serves a page over the history file on localhost, the
+// file is read again on every request so it follows the runs being recorded
+func dashboardCommand(args []string) {
+	fs := flag.NewFlagSet("dashboard", flag.ExitOnError)
+	historyPath := fs.String("history", "", "history file written by --history (default history_file from the config)")
+	configPath := fs.String("config", "", "config file (default verifyshuho/config.json in the user config directory)")
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	open := fs.Bool("open", false, "open the dashboard in the default browser")
+	fs.BoolVar(&fiscalYearf, "fiscal-year", false, "total the years from April to March")
+	fs.Parse(args)
+
+	var err error
+	if config, err = loadConfig(*configPath); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return
+	}
+	if *historyPath == "" {
+		*historyPath = config.HistoryFile
+	}
+	if *historyPath == "" {
+		fmt.Fprintln(out, "\033[1;31mERROR Usage:\033[0m ./verifyshuho dashboard --history history.jsonl [OPTIONS]")
+		fs.PrintDefaults()
+		return
+	}
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		records, err := loadHistory(*historyPath)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var page bytes.Buffer
+		if err := dashboardTemplate.Execute(&page, newDashboard(*historyPath, records)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page.Bytes())
+	})
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return
+	}
+	url := "http://" + l.Addr().String() + "/"
+	printer.Fprintf(out, "Serving the dashboard for %s on %s\n", *historyPath, url)
+	if *open {
+		if err := openInBrowser(url); err != nil {
+			fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		}
+	}
+	if err := http.Serve(l, nil); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+	}
+}
+
+// dashboardTally is the work of one client or case over every month
+type dashboardTally struct {
+	Name    string
+	Entries int
+	Words   int
+	Amount  float64
+}
+
+// dashboardYear is the totals of a calendar year, or of the fiscal year
+// starting in April of it with --fiscal-year
+type dashboardYear struct {
+	Year         int
+	Months       int
+	Translations float64
+	Checks       float64
+	Pretax       float64
+}
+
+type dashboard struct {
+	History    string
+	Months     []HistoryRecord
+	Years      []dashboardYear
+	FiscalYear bool
+	Charts     []template.HTML
+	Clients    []*dashboardTally
+	Cases      []*dashboardTally
+	// the runs since the result last changed, and the longest passing run of runs
+	Streak        int
+	StreakPassed  bool
+	LongestPassed int
+}
+
+// topCases is how many cases the dashboard lists
+const topCases = 10
+
+// newDashboard sums the latest run of every month. Clients are the case
+// number prefixes, ALP for ALP-1234.
+func newDashboard(path string, records []HistoryRecord) dashboard {
+	d := dashboard{History: path, Months: latestByMonth(records), FiscalYear: fiscalYearf}
+
+	//the months are in order, a team's authors share a month
+	var lastMonth string
+	for _, record := range d.Months {
+		if len(d.Years) == 0 || d.Years[len(d.Years)-1].Year != yearOf(record.Month) {
+			d.Years = append(d.Years, dashboardYear{Year: yearOf(record.Month)})
+		}
+		y := &d.Years[len(d.Years)-1]
+		if record.Month != lastMonth {
+			y.Months++
+			lastMonth = record.Month
+		}
+		y.Translations += record.Translations
+		y.Checks += record.Checks
+		y.Pretax += record.Pretax
+	}
+
+	for _, chart := range historyCharts(records)[:2] {
+		var svg bytes.Buffer
+		writeSVGChart(&svg, chart)
+		d.Charts = append(d.Charts, template.HTML(svg.String()))
+	}
+
+	clients := make(map[string]*dashboardTally)
+	cases := make(map[string]*dashboardTally)
+	tally := func(tallies map[string]*dashboardTally, name string, words int, amount float64) {
+		t, ok := tallies[name]
+		if !ok {
+			t = &dashboardTally{Name: name}
+			tallies[name] = t
+		}
+		t.Entries++
+		t.Words += words
+		t.Amount += amount
+	}
+	for _, record := range d.Months {
+		for _, e := range record.Entries {
+			words, _ := strconv.Atoi(strings.ReplaceAll(e.Words, ",", ""))
+			rate, _ := strconv.ParseFloat(e.Rate, 64)
+			client, _, _ := strings.Cut(e.Case, "-")
+			tally(clients, client, words, float64(words)*rate)
+			tally(cases, e.Case, words, float64(words)*rate)
+		}
+	}
+	d.Clients = sortedTallies(clients)
+	d.Cases = sortedTallies(cases)
+	if len(d.Cases) > topCases {
+		d.Cases = d.Cases[:topCases]
+	}
+
+	var passing int
+	for i, record := range records {
+		if i == 0 || record.Passed != d.StreakPassed {
+			d.Streak = 0
+		}
+		d.Streak++
+		d.StreakPassed = record.Passed
+		if passing = 0; record.Passed {
+			passing = d.Streak
+		}
+		if passing > d.LongestPassed {
+			d.LongestPassed = passing
+		}
+	}
+
+	return d
+}
+
+// sortedTallies are the tallies by words, most first
+func sortedTallies(tallies map[string]*dashboardTally) []*dashboardTally {
+	sorted := make([]*dashboardTally, 0, len(tallies))
+	for _, t := range tallies {
+		sorted = append(sorted, t)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Words != sorted[j].Words {
+			return sorted[i].Words > sorted[j].Words
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	return sorted
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"yen":    formatYen,
+	"number": func(n int) string { return printer.Sprintf("%d", n) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>verifyshuho dashboard</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+h2 { font-size: 1.1em; margin-top: 1.5em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; }
+td.n { text-align: right; }
+.passed { color: #1a7f37; }
+.failed { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>verifyshuho dashboard</h1>
+<p>{{.History}}: {{len .Months}} months.
+{{if .Streak}}The last {{.Streak}} runs <span class="{{if .StreakPassed}}passed">passed{{else}}failed">failed{{end}}</span>, the longest passing streak is {{.LongestPassed}} runs.{{end}}</p>
+{{range .Charts}}{{.}}{{end}}
+<h2>Monthly totals</h2>
+<table>
+<tr><th>Month</th><th>Translations</th><th>Checks</th><th>Pre-tax total</th><th>Entries</th><th>Last run</th></tr>
+{{range .Months}}<tr><td>{{.Month}}</td><td class="n">{{yen .Translations}}</td><td class="n">{{yen .Checks}}</td><td class="n">{{yen .Pretax}}</td><td class="n">{{len .Entries}}</td><td class="{{if .Passed}}passed">passed{{else}}failed">failed{{end}}</td></tr>
+{{end}}</table>
+<h2>{{if .FiscalYear}}Fiscal year totals, April to March{{else}}Yearly totals{{end}}</h2>
+<table>
+<tr><th>Year</th><th>Months</th><th>Translations</th><th>Checks</th><th>Pre-tax total</th></tr>
+{{range .Years}}<tr><td>{{if $.FiscalYear}}FY{{end}}{{.Year}}</td><td class="n">{{.Months}}</td><td class="n">{{yen .Translations}}</td><td class="n">{{yen .Checks}}</td><td class="n">{{yen .Pretax}}</td></tr>
+{{end}}</table>
+<h2>Per client</h2>
+<table>
+<tr><th>Client</th><th>Entries</th><th>Words</th><th>Amount</th></tr>
+{{range .Clients}}<tr><td>{{.Name}}</td><td class="n">{{.Entries}}</td><td class="n">{{number .Words}}</td><td class="n">{{yen .Amount}}</td></tr>
+{{end}}</table>
+<h2>Top cases by volume</h2>
+<table>
+<tr><th>Case</th><th>Entries</th><th>Words</th><th>Amount</th></tr>
+{{range .Cases}}<tr><td>{{.Name}}</td><td class="n">{{.Entries}}</td><td class="n">{{number .Words}}</td><td class="n">{{yen .Amount}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardCommands are tried in order, the first one installed is used
+var clipboardCommands = map[string][][]string{
+	"darwin":  {{"pbcopy"}},
+	"windows": {{"clip.exe"}},
+	"linux":   {{"wl-copy"}, {"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}},
+}
+
+func copyToClipboard(text string) error {
+	for _, command := range clipboardCommands[runtime.GOOS] {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+
+		cmd := exec.Command(path, command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+
+	return errors.New("no clipboard command found (pbcopy, clip.exe, wl-copy, xclip or xsel)")
+}
+
+// openInBrowser shows path in the default browser without waiting for it
+func openInBrowser(path string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", path)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+	default:
+		cmd = exec.Command("xdg-open", path)
+	}
+
+	return cmd.Start()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// outlierFactor is how many times off the usual word count of a type an
+// entry has to be to look like a missing decimal or an extra digit
+const outlierFactor = 10
+
+// minOutlierHistory is how many earlier entries of a type it takes before
+// the outlier check trusts their distribution
+const minOutlierHistory = 10
+
+// wordStats describe how the word counts of one type are spread
+type wordStats struct {
+	Count  int
+	Min    float64
+	Max    float64
+	Median float64
+	Mean   float64
+	P90    float64
+}
+
+// describeWords sums up the word counts, sorting them in place
+func describeWords(words []float64) wordStats {
+	if len(words) == 0 {
+		return wordStats{}
+	}
+	sort.Float64s(words)
+	var sum float64
+	for _, w := range words {
+		sum += w
+	}
+	median := words[len(words)/2]
+	if len(words)%2 == 0 {
+		median = (words[len(words)/2-1] + words[len(words)/2]) / 2
+	}
+
+	return wordStats{
+		Count:  len(words),
+		Min:    words[0],
+		Max:    words[len(words)-1],
+		Median: median,
+		Mean:   sum / float64(len(words)),
+		// nearest rank, so the p90 is always one of the word counts
+		P90: words[int(math.Ceil(0.9*float64(len(words))))-1],
+	}
+}
+
+// wordsOfType are the word counts of the entries of the type
+func wordsOfType(entries []Entry, eType string) []float64 {
+	var words []float64
+	for _, e := range entries {
+		if e.Type() != eType {
+			continue
+		}
+		if w, err := strconv.ParseFloat(e.WordCount(), 64); err == nil {
+			words = append(words, w)
+		}
+	}
+
+	return words
+}
+
+// printWordStats prints the word count spread of translations and checks,
+// to see whether the month was many small jobs or a few large ones
+func printWordStats(entries []Entry) {
+	printer.Fprintf(out, "Word counts this month:\n")
+	for _, eType := range []string{"翻訳", "英文チェック"} {
+		s := describeWords(wordsOfType(entries, eType))
+		if s.Count == 0 {
+			continue
+		}
+		printer.Fprintf(out, "  %s: min %.0f, median %.0f, mean %.0f, p90 %.0f, max %.0f\n",
+			typeLabel(eType), s.Min, s.Median, s.Mean, s.P90, s.Max)
+	}
+}
+
+// checkWordCountOutliers warns about invoice entries whose word count is
+// outlierFactor times above or below the median of the type in the other
+// months of the history and outside anything invoiced before
+func checkWordCountOutliers(invoice []Entry, records []HistoryRecord) []Finding {
+	if len(invoice) == 0 {
+		return nil
+	}
+	month := invoice[0].Date().Format("2006-01")
+
+	past := make(map[string][]float64)
+	for _, record := range latestByMonth(records) {
+		if record.Month == month {
+			continue
+		}
+		for _, e := range record.Entries {
+			if w, err := strconv.ParseFloat(normalizeNumber(e.Words), 64); err == nil {
+				past[e.Type] = append(past[e.Type], w)
+			}
+		}
+	}
+	usual := make(map[string]wordStats)
+	for eType, words := range past {
+		if len(words) >= minOutlierHistory {
+			usual[eType] = describeWords(words)
+		}
+	}
+
+	var findings []Finding
+	for _, e := range invoice {
+		s, ok := usual[e.Type()]
+		words, err := strconv.ParseFloat(e.WordCount(), 64)
+		if !ok || err != nil {
+			continue
+		}
+		if (words > s.Median*outlierFactor && words > s.Max) || (words < s.Median/outlierFactor && words < s.Min) {
+			findings = append(findings, Finding{Message: printer.Sprintf("Word count %s is far from the usual %.0f of %s: Row %s", e.WordCount(), s.Median, typeLabel(e.Type()), e.String()), Entry: e, Severity: SeverityWarning})
+		}
+	}
+
+	return findings
+}
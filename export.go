@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportedEntry is an Entry as written by the export command, shuho and
+// invoice entries share the record and leave the other kind's fields empty
+type exportedEntry struct {
+	Kind  string `json:"kind"`
+	File  string `json:"file"`
+	Sheet string `json:"sheet"`
+	Row   int    `json:"row"`
+	Date  string `json:"date"`
+	Case  string `json:"case"`
+	Type  string `json:"type"`
+	// the word count of the type, the one the checks compare
+	Words string `json:"words"`
+	// invoice row number and rate
+	No   string `json:"no,omitempty"`
+	Rate string `json:"rate,omitempty"`
+	// shuho word count columns and author
+	TranslationWords string `json:"translation_words,omitempty"`
+	CheckWords       string `json:"check_words,omitempty"`
+	Author           string `json:"author,omitempty"`
+	// the po column when mapped
+	PO string `json:"po,omitempty"`
+	// the invoice client column when mapped
+	Client string `json:"client,omitempty"`
+}
+
+var exportColumns = []string{"kind", "file", "sheet", "row", "date", "case", "type", "words", "no", "rate", "translation_words", "check_words", "author", "po", "client"}
+
+func newExportedEntry(e Entry) exportedEntry {
+	loc := e.Location()
+	exported := exportedEntry{File: loc.File, Sheet: loc.Sheet, Row: loc.Row, Date: e.Date().Format("2006-01-02"), Case: e.CaseNum(), Type: e.Type(), Words: e.WordCount()}
+
+	switch e := e.(type) {
+	case InvoiceEntry:
+		exported.Kind, exported.No, exported.Rate, exported.PO, exported.Client = "invoice", e.rowNum, e.rate, e.po, e.client
+	case ShuhoEntry:
+		exported.Kind, exported.TranslationWords, exported.CheckWords, exported.Author = "shuho", e.STWordCount, e.SCWordCount, e.SAuthor
+		exported.PO = e.SPO
+	}
+
+	return exported
+}
+
+func (e exportedEntry) record() []string {
+	return []string{e.Kind, e.File, e.Sheet, strconv.Itoa(e.Row), e.Date, e.Case, e.Type, e.Words, e.No, e.Rate, e.TranslationWords, e.CheckWords, e.Author, e.PO, e.Client}
+}
+
+// exportCommand writes the parsed entries of a workbook as JSON or CSV, for
+// scripts that want the data without the workbook quirks
+func exportCommand(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	kind := fs.String("kind", "", "workbook kind: shuho or invoice (detected when empty)")
+	format := fs.String("format", "json", "json or csv")
+	output := fs.String("o", "", "write to `file` instead of stdout")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || (*format != "json" && *format != "csv") {
+		printer.Fprintf(out, "\033[1;31mERROR Usage:\033[0m ./verifyshuho export [--kind shuho|invoice] [--format json|csv] [-o file] <workbook.xlsx>\n")
+		return
+	}
+
+	f, err := openWorkbook(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return
+	}
+	defer f.Close()
+
+	if *kind == "" {
+		*kind = detectWorkbookKind(f)
+	}
+
+	var entries []Entry
+	switch *kind {
+	case "shuho":
+		entries, err = parseShuho(f)
+	case "invoice":
+		entries, err = parseInvoice(f)
+	default:
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m Unknown workbook kind %q\n", *kind)
+		return
+	}
+	//rows that don't parse are left out, like in a verification
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+			return
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := writeExport(w, *format, entries); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+	}
+}
+
+func writeExport(w io.Writer, format string, entries []Entry) error {
+	exported := make([]exportedEntry, 0, len(entries))
+	for _, e := range entries {
+		exported = append(exported, newExportedEntry(e))
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(exported)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(exportColumns)
+	for _, e := range exported {
+		cw.Write(e.record())
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// exportFile is a file written by the export command, or any JSON or CSV
+// with its columns, standing in for a workbook on either side of a
+// verification. Which side it is on decides what its entries are.
+type exportFile struct {
+	path    string
+	entries []exportedEntry
+}
+
+func (f *exportFile) Name() string {
+	return f.path
+}
+
+func (f *exportFile) Sheets() []string {
+	return nil
+}
+
+func (f *exportFile) SheetRows(sheet string) ([][]string, error) {
+	return nil, fmt.Errorf("%s: exported entries have no sheets", f.path)
+}
+
+func (f *exportFile) Close() error {
+	return nil
+}
+
+func openExport(path string) (Workbook, error) {
+	data, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+
+	return readExport(path, data)
+}
+
+// readExport reads JSON when path ends in .json and CSV otherwise
+func readExport(path string, r io.Reader) (Workbook, error) {
+	var err error
+	f := &exportFile{path: path}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.NewDecoder(r).Decode(&f.entries)
+	} else {
+		f.entries, err = readExportCSV(r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return f, nil
+}
+
+// readExportCSV reads the columns by their header, only date, case, type
+// and words are needed so exports of other systems can be used too
+func readExportCSV(r io.Reader) ([]exportedEntry, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	index := make(map[string]int)
+	for i, name := range records[0] {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "case", "type", "words"} {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("no %s column", name)
+		}
+	}
+
+	var entries []exportedEntry
+	for i, record := range records[1:] {
+		field := func(name string) string {
+			if j, ok := index[name]; ok && j < len(record) {
+				return record[j]
+			}
+			return ""
+		}
+		row, _ := strconv.Atoi(field("row"))
+		if row == 0 {
+			row = i + 2
+		}
+		entries = append(entries, exportedEntry{
+			Kind: field("kind"), File: field("file"), Sheet: field("sheet"), Row: row,
+			Date: field("date"), Case: field("case"), Type: field("type"), Words: field("words"),
+			No: field("no"), Rate: field("rate"),
+			TranslationWords: field("translation_words"), CheckWords: field("check_words"), Author: field("author"),
+			PO: field("po"), Client: field("client"),
+		})
+	}
+
+	return entries, nil
+}
+
+// parse turns the exported entries into invoice or shuho entries, entries
+// keep the location they were exported from
+func (f *exportFile) parse(invoice bool) ([]Entry, error) {
+	var entries []Entry
+	var errs []error
+
+	for i, e := range f.entries {
+		loc := Location{File: e.File, Sheet: e.Sheet, Row: e.Row}
+		if loc.File == "" {
+			loc.File = f.path
+		}
+		if loc.Row == 0 {
+			loc.Row = i + 1
+		}
+
+		//other systems' exports may use the invoice or shuho date styles
+		date, err := time.Parse("2006-01-02", e.Date)
+		if err != nil {
+			date, err = getDate(e.Date)
+		}
+		if err != nil {
+			errs = append(errs, &ParseError{loc, fmt.Errorf("invalid date %s", e.Date)})
+			continue
+		}
+		words := normalizeNumber(e.Words)
+
+		if invoice {
+			entries = append(entries, InvoiceEntry{rowNum: e.No, IDate: date, ICaseNum: e.Case, IType: e.Type, IWordCount: words, rate: e.Rate, po: e.PO, client: e.Client, loc: loc})
+			continue
+		}
+
+		se := ShuhoEntry{SDate: date, SCaseNum: e.Case, SType: e.Type, STWordCount: normalizeNumber(e.TranslationWords), SCWordCount: normalizeNumber(e.CheckWords), SAuthor: e.Author, SPO: e.PO, loc: loc}
+		if se.STWordCount == "" && se.SCWordCount == "" {
+			switch e.Type {
+			case "英文チェック":
+				se.SCWordCount = words
+			default:
+				se.STWordCount = words
+			}
+		}
+		entries = append(entries, se)
+	}
+
+	return entries, errors.Join(errs...)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity of a finding, errors always fail the run and info never does
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+
+	return "error"
+}
+
+func parseSeverity(name string) (Severity, bool) {
+	switch strings.ToLower(name) {
+	case "error":
+		return SeverityError, true
+	case "warning":
+		return SeverityWarning, true
+	case "info":
+		return SeverityInfo, true
+	}
+
+	return SeverityError, false
+}
+
+// Finding is one problem a check found, Entry is the row it concerns.
+// Findings about rows that never became entries only carry Loc.
+type Finding struct {
+	Message  string
+	Entry    Entry
+	Severity Severity
+	Loc      Location
+	// the field of the entry's row the finding is about, rate say, its cell
+	// is the one the report links to
+	Field string
+}
+
+func (f Finding) Location() Location {
+	if f.Entry != nil {
+		loc := f.Entry.Location()
+		loc.Col = fieldColumn(f.Entry, f.Field)
+		return loc
+	}
+
+	return f.Loc
+}
+
+// fieldColumn is the column of field in the row of e, 0 when its columns
+// aren't known
+func fieldColumn(e Entry, field string) int {
+	var columns *columnMapping
+	switch e := e.(type) {
+	case InvoiceEntry:
+		columns = e.columns
+	case ShuhoEntry:
+		columns = e.columns
+	}
+	if columns == nil || field == "" || columns.field(field) < 0 {
+		return 0
+	}
+
+	return columns.cols[columns.field(field)] + 1
+}
+
+// CheckResult is the outcome of one check, info findings don't stop it passing
+type CheckResult struct {
+	ID       string
+	Name     string
+	Findings []Finding
+}
+
+func (r CheckResult) Passed() bool {
+	for _, finding := range r.Findings {
+		if finding.Severity != SeverityInfo {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applySeverities overrides the severity of every finding of the configured checks
+func applySeverities(results []CheckResult, severities map[string]string) {
+	for _, result := range results {
+		severity, ok := parseSeverity(severities[result.ID])
+		if !ok {
+			continue
+		}
+		for i := range result.Findings {
+			result.Findings[i].Severity = severity
+		}
+	}
+}
+
+// markerSet is how check results are marked, chosen with --markers
+type markerSet struct {
+	ok       string
+	severity map[Severity]string
+}
+
+var markerSets = map[string]markerSet{
+	"ascii": {"OKAY...", map[Severity]string{
+		SeverityError:   "\033[1;31mERROR:\033[0m",
+		SeverityWarning: "\033[1;33mWARNING:\033[0m",
+		SeverityInfo:    "\033[1;34mINFO:\033[0m",
+	}},
+	"emoji": {"✓", map[Severity]string{
+		SeverityError:   "✗",
+		SeverityWarning: "⚠",
+		SeverityInfo:    "ℹ",
+	}},
+	"plain": {"ok:", map[Severity]string{
+		SeverityError:   "error:",
+		SeverityWarning: "warning:",
+		SeverityInfo:    "info:",
+	}},
+}
+
+// markerStyle is the --markers flag, one of the markerSets
+type markerStyle string
+
+func (m *markerStyle) String() string {
+	return string(*m)
+}
+
+func (m *markerStyle) Set(value string) error {
+	if _, ok := markerSets[value]; !ok {
+		return fmt.Errorf("unknown markers %q, use ascii, emoji or plain", value)
+	}
+	*m = markerStyle(value)
+
+	return nil
+}
+
+var markersf = markerStyle("ascii")
+
+func markers() markerSet {
+	return markerSets[string(markersf)]
+}
+
+func printCheckResults(results []CheckResult) {
+	for _, result := range results {
+		for _, finding := range result.Findings {
+			message := finding.Message
+			//every finding in a workbook says where, most messages only describe the entry
+			if loc := finding.Location(); loc.File != "" && !strings.Contains(message, loc.String()) {
+				message = printer.Sprintf("%s at %s", message, loc)
+			}
+			fmt.Fprintf(out, "%s %s\n", markers().severity[finding.Severity], link(message, finding.Location()))
+		}
+
+		if result.Passed() {
+			showCheckSuccess(translate(result.Name))
+		}
+	}
+}
+
+// runFailed is true when there are errors, or warnings past --max-warnings (any with --strict)
+func runFailed(errors, warnings int) bool {
+	return errors > 0 || (strictf && warnings > 0) || (maxWarningsf >= 0 && warnings > maxWarningsf)
+}
+
+// exitStatus is 1 when there are errors, or warnings past --max-warnings (any with --strict)
+func exitStatus(results []CheckResult) int {
+	var errors, warnings int
+
+	for _, result := range results {
+		for _, finding := range result.Findings {
+			switch finding.Severity {
+			case SeverityError:
+				errors++
+			case SeverityWarning:
+				warnings++
+			}
+		}
+	}
+
+	if !runFailed(errors, warnings) {
+		return 0
+	}
+
+	printer.Fprintf(out, "\n\033[1;31mFAILED:\033[0m %d errors, %d warnings\n", errors, warnings)
+	return 1
+}
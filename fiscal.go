@@ -0,0 +1,23 @@
+package main
+
+import "strconv"
+
+// fiscalYearf counts the year to date totals from April to March, the
+// Japanese fiscal year, instead of January to December
+var fiscalYearf bool
+
+// yearOf is the year a YYYY-MM month counts in, with --fiscal-year the year
+// the fiscal year started in, so 2024-03 is in 2023
+func yearOf(month string) int {
+	year, _ := strconv.Atoi(month[:4])
+	if fiscalYearf && month[5:7] < "04" {
+		year--
+	}
+
+	return year
+}
+
+// sameYear is true for months counted in the same calendar or fiscal year
+func sameYear(a, b string) bool {
+	return yearOf(a) == yearOf(b)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// fixf is what --fix repairs: shuho adds the invoice entries missing from
+// it, invoice corrects the mistakes the checks can prove
+var fixf string
+
+// fixOutputf is where the fixed workbook is written, next to it by default
+var fixOutputf string
+
+// dryRunf lists the changes --fix would make without writing them
+var dryRunf bool
+
+func defaultFixOutput(path string) string {
+	ext := filepath.Ext(path)
+
+	return strings.TrimSuffix(path, ext) + ".fixed" + ext
+}
+
+// fixChanges are the changes of the running fix as listed, for the journal
+var fixChanges []string
+
+func printFixChange(format string, a ...interface{}) {
+	printer.Fprintf(out, format, a...)
+	fixChanges = append(fixChanges, strings.TrimSuffix(printer.Sprintf(format, a...), "\n"))
+}
+
+// saveFix writes the fixed workbook and journals it so undo can restore
+// what was at output before
+func saveFix(w xlsxWorkbook, kind, output string) error {
+	_, err := os.Stat(output)
+	created := errors.Is(err, os.ErrNotExist)
+	backup, err := saveWorkbook(w.f, output)
+	if err != nil {
+		return err
+	}
+
+	//undo may run from another directory
+	session := fixSession{Time: time.Now().Format(time.RFC3339), Kind: kind, Source: w.Name(), Output: output, Backup: backup, Created: created, Changes: fixChanges}
+	for _, path := range []*string{&session.Source, &session.Output, &session.Backup} {
+		if *path != "" {
+			if *path, err = filepath.Abs(*path); err != nil {
+				return err
+			}
+		}
+	}
+	if session.SHA256, err = fileChecksum(output); err != nil {
+		return err
+	}
+
+	return appendFixSession(fixJournalPath(), session)
+}
+
+// fixTarget is the workbook --fix changes and the file the result goes to
+func fixTarget(f Workbook, kind string) (xlsxWorkbook, string, error) {
+	w, ok := f.(xlsxWorkbook)
+	if !ok || isRemote(f.Name()) {
+		return w, "", fmt.Errorf("--fix %s needs a local .xlsx %s", kind, kind)
+	}
+	if fixOutputf != "" {
+		return w, fixOutputf, nil
+	}
+	if f.Name() == "" || f.Name() == "-" {
+		return w, "", fmt.Errorf("--fix %s of a piped workbook needs --fix-output", kind)
+	}
+
+	return w, defaultFixOutput(f.Name()), nil
+}
+
+// findingEntries are the invoice entries of the findings of check id,
+// errors only unless warnings is set
+func findingEntries(results []CheckResult, id string, warnings bool) []InvoiceEntry {
+	var entries []InvoiceEntry
+	for _, result := range results {
+		if result.ID != id {
+			continue
+		}
+		for _, finding := range result.Findings {
+			if ie, ok := finding.Entry.(InvoiceEntry); ok && (finding.Severity == SeverityError || warnings && finding.Severity == SeverityWarning) {
+				entries = append(entries, ie)
+			}
+		}
+	}
+
+	return entries
+}
+
+// missingFromShuho are the invoice entries the invoice-in-shuho check
+// reports as missing, not the ones only off by a few words
+func missingFromShuho(results []CheckResult) []InvoiceEntry {
+	return findingEntries(results, "invoice-in-shuho", false)
+}
+
+// fixShuho adds the missing invoice entries to the shuho and saves it to
+// fixOutputf. Each one is a copy of a dated row of its month's sheet, so it
+// keeps the formatting, placed in date order and noted as added.
+func fixShuho(fshuho Workbook, missing []InvoiceEntry) error {
+	w, output, err := fixTarget(fshuho, "shuho")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "")
+	colorize(ColorGreen, translate("** Fixing the shuho: "))
+	fixChanges = nil
+	var added int
+	for _, ie := range missing {
+		var words int
+		switch ie.IType {
+		case "翻訳", "英文チェック":
+			words, _ = strconv.Atoi(ie.IWordCount)
+		default:
+			printer.Fprintf(out, "\033[1;33mSKIPPED:\033[0m Unknown type %q, add by hand: Row %s\n", ie.IType, ie.String())
+			continue
+		}
+
+		sheet := fixSheet(w.f, ie)
+		if sheet == "" {
+			printer.Fprintf(out, "\033[1;33mSKIPPED:\033[0m No sheet for %s in the shuho, add by hand: Row %s\n", ie.IDate.Format("2006-01"), ie.String())
+			continue
+		}
+		row, err := insertShuhoRow(w.f, sheet, ie, words)
+		if err != nil {
+			return err
+		}
+		added++
+		printFixChange("Added Row %s at %s\n", ie.String(), Location{File: output, Sheet: sheet, Row: row})
+		rows, _ := w.f.GetRows(sheet)
+		columns := shuhoColumnsFor(sheet, rows)
+		for _, field := range columns.fields {
+			if !columns.mapped(field) {
+				continue
+			}
+			cell, _ := excelize.CoordinatesToCellName(columns.cols[columns.field(field)]+1, row)
+			if value, _ := w.f.GetCellValue(sheet, cell); value != "" {
+				printFixChange("Changed %s: %s → %s (%s)\n", cellRef(sheet, cell), "", value, translate("new row"))
+			}
+		}
+	}
+
+	if added == 0 {
+		printer.Fprintf(out, "Nothing to add\n")
+		return nil
+	}
+	if dryRunf {
+		printer.Fprintf(out, "Dry run, %s not written\n", output)
+		return nil
+	}
+	if err := saveFix(w, "shuho", output); err != nil {
+		return err
+	}
+	printer.Fprintf(out, "Wrote the fixed shuho with %d added entries to %s\n", added, output)
+
+	return nil
+}
+
+// fixSheet is the sheet named after the month of ie, of weekly sheets named
+// by their Monday the last one starting on or before the entry
+func fixSheet(f *excelize.File, ie InvoiceEntry) string {
+	var found string
+	for _, name := range f.GetSheetList() {
+		month, ok := sheetMonth(name)
+		if !ok || templateSheetName(name) || month.Year() != ie.IDate.Year() || month.Month() != ie.IDate.Month() {
+			continue
+		}
+		if day, ok := sheetDay(name); ok && day > ie.IDate.Day() {
+			continue
+		}
+		found = name
+	}
+
+	return found
+}
+
+// sheetDay is the day in a sheet name such as 2023-06-19
+func sheetDay(name string) (int, bool) {
+	m := sheetMonthRe.FindStringIndex(name)
+	if m == nil {
+		return 0, false
+	}
+	rest := strings.TrimLeft(name[m[1]:], "-/._日 ")
+	var digits int
+	for digits < len(rest) && digits < 2 && rest[digits] >= '0' && rest[digits] <= '9' {
+		digits++
+	}
+	day, err := strconv.Atoi(rest[:digits])
+
+	return day, err == nil && day >= 1 && day <= 31
+}
+
+// insertShuhoRow writes ie after the last row of the sheet dated on or
+// before it and returns the 1-based row it went to
+func insertShuhoRow(f *excelize.File, sheet string, ie InvoiceEntry, words int) (int, error) {
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return 0, err
+	}
+
+	//the rows are 1-based, template is the dated row copied for the formatting
+	columns := shuhoColumnsFor(sheet, rows)
+	at, template := len(rows)+1, 0
+	for i, row := range rows {
+		row = columns.apply(row)
+		if !checkForValidDate(row[0]) {
+			continue
+		}
+		date, err := getDate(row[0])
+		if err != nil {
+			continue
+		}
+		if date.After(ie.IDate) {
+			if template == 0 {
+				at, template = i+1, i+1
+			}
+			break
+		}
+		at, template = i+2, i+1
+	}
+
+	col := func(field string, row int) string {
+		name, _ := excelize.CoordinatesToCellName(columns.cols[columns.field(field)]+1, row)
+		return name
+	}
+	numeric := func(cell string) bool {
+		return cellIsNumber(f, sheet, cell)
+	}
+
+	var author string
+	switch {
+	case template == 0:
+		err = f.InsertRows(sheet, at, 1)
+	case template == at:
+		//before the first dated row, the copy goes below it and the row itself is overwritten
+		author, _ = f.GetCellValue(sheet, col("author", template))
+		err = f.DuplicateRowTo(sheet, template, at+1)
+	default:
+		author, _ = f.GetCellValue(sheet, col("author", template))
+		err = f.DuplicateRowTo(sheet, template, at)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	wordsField, otherField := "translation", "check"
+	if ie.IType == "英文チェック" {
+		wordsField, otherField = "check", "translation"
+	}
+
+	//a typed cell of the copied row stays typed, text stays text
+	if numeric(col("date", at)) {
+		err = f.SetCellValue(sheet, col("date", at), ie.IDate)
+	} else {
+		err = f.SetCellStr(sheet, col("date", at), fmt.Sprintf("%d/%d", ie.IDate.Month(), ie.IDate.Day()))
+	}
+	if err == nil && (numeric(col(wordsField, at)) || numeric(col(otherField, at))) {
+		err = f.SetCellInt(sheet, col(wordsField, at), words)
+	} else if err == nil {
+		err = f.SetCellStr(sheet, col(wordsField, at), strconv.Itoa(words))
+	}
+	for _, cell := range [][2]string{
+		{col("case", at), ie.ICaseNum},
+		{col("type", at), ie.IType},
+		{col(otherField, at), ""},
+		{col("note", at), printer.Sprintf("added from invoice row %s", ie.rowNum)},
+		{col("author", at), author},
+	} {
+		if err == nil {
+			err = f.SetCellStr(sheet, cell[0], cell[1])
+		}
+	}
+	if columns.mapped("po") && err == nil {
+		err = f.SetCellStr(sheet, col("po", at), ie.po)
+	}
+
+	return at, err
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// fixInvoice corrects what the checks prove wrong on the invoice: rates that
+// aren't the rate of the type, word counts that only differ from the one
+// shuho entry of the same case and type, and rows repeating an earlier one
+// exactly. Every change is listed and the workbook saved to a copy.
+func fixInvoice(finvoice Workbook, shuhoEntries, invoiceEntries []Entry, results []CheckResult) error {
+	w, output, err := fixTarget(finvoice, "invoice")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "")
+	colorize(ColorGreen, translate("** Fixing the invoice: "))
+	fixChanges = nil
+
+	removed := exactDuplicates(invoiceEntries, findingEntries(results, "duplicates", false))
+	var changes int
+	change := func(ie InvoiceEntry, field, value, reason string) error {
+		if _, ok := removed[ie.loc.Row]; ok {
+			return nil
+		}
+		cell, _ := excelize.CoordinatesToCellName(invoiceColumns.cols[invoiceColumns.field(field)]+1, ie.loc.Row)
+		old, err := w.f.GetCellValue(ie.loc.Sheet, cell)
+		if err != nil {
+			return err
+		}
+		if err := setCellLike(w.f, ie.loc.Sheet, cell, value); err != nil {
+			return err
+		}
+		changes++
+		printFixChange("Changed %s: %s → %s (%s)\n", cellRef(ie.loc.Sheet, cell), old, value, translate(reason))
+
+		return nil
+	}
+
+	for _, ie := range findingEntries(results, "rates", false) {
+		if rate, ok := expectedRate(ie); ok {
+			if err := change(ie, "rate", rate, "rate of the type"); err != nil {
+				return err
+			}
+		}
+	}
+	for _, fix := range shuhoWordCounts(shuhoEntries, invoiceEntries, findingEntries(results, "invoice-in-shuho", true)) {
+		if err := change(fix.entry, "words", fix.words, "word count in the shuho"); err != nil {
+			return err
+		}
+	}
+
+	//bottom up so the rows still to remove keep their numbers, the rows
+	//below each one move up and their No. goes down by one
+	var rows []int
+	for row := range removed {
+		rows = append(rows, row)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(rows)))
+	sheet := invoiceSheet(invoiceEntries)
+	for _, row := range rows {
+		if err := w.f.RemoveRow(sheet, row); err != nil {
+			return err
+		}
+		changes++
+		printFixChange("Removed duplicate row %s\n", cellRef(sheet, strconv.Itoa(row)+":"+strconv.Itoa(row)))
+	}
+	if len(rows) > 0 {
+		renumbered, err := renumberInvoice(w.f, invoiceEntries, removed)
+		if err != nil {
+			return err
+		}
+		changes += renumbered
+	}
+
+	if changes == 0 {
+		printer.Fprintf(out, "Nothing to change\n")
+		return nil
+	}
+	if dryRunf {
+		printer.Fprintf(out, "Dry run, %s not written\n", output)
+		return nil
+	}
+	if err := saveFix(w, "invoice", output); err != nil {
+		return err
+	}
+	printer.Fprintf(out, "Wrote the fixed invoice with %d changes to %s\n", changes, output)
+
+	return nil
+}
+
+// invoiceSheet is the sheet the invoice entries were read from
+func invoiceSheet(entries []Entry) string {
+	return entries[0].Location().Sheet
+}
+
+// exactDuplicates are the rows of the duplicates that repeat every field of
+// an earlier row, not only its signature. A duplicate numbered on from the
+// rows above it is true, the numbering below it moves up when it goes.
+func exactDuplicates(entries []Entry, duplicates []InvoiceEntry) map[int]bool {
+	rows := make(map[int]bool)
+	for _, dup := range duplicates {
+		for _, e := range entries {
+			ie, ok := e.(InvoiceEntry)
+			if ok && ie.loc.Row < dup.loc.Row && ie.ICaseNum == dup.ICaseNum && ie.IType == dup.IType &&
+				ie.IDate.Equal(dup.IDate) && ie.IWordCount == dup.IWordCount && ie.rate == dup.rate {
+				rows[dup.loc.Row] = dup.rowNum != ie.rowNum
+				break
+			}
+		}
+	}
+
+	return rows
+}
+
+type wordCountFix struct {
+	entry InvoiceEntry
+	words string
+}
+
+// shuhoWordCounts pairs the invoice entries not in the shuho with the shuho
+// entry of the same case and type no invoice entry matches, when there is
+// exactly one such entry
+func shuhoWordCounts(shuhoEntries, invoiceEntries []Entry, missing []InvoiceEntry) []wordCountFix {
+	invoiced := make(map[string]bool)
+	for _, ie := range invoiceEntries {
+		invoiced[ie.signature()] = true
+	}
+	unmatched := make(map[string][]Entry)
+	for _, se := range matchable(getScopedShuho(shuhoEntries, invoiceEntries)) {
+		if !invoiced[se.signature()] {
+			key := matchCase(se.CaseNum()) + " " + se.Type()
+			unmatched[key] = append(unmatched[key], se)
+		}
+	}
+
+	var fixes []wordCountFix
+	claimed := make(map[string]int)
+	for _, ie := range missing {
+		claimed[matchCase(ie.ICaseNum)+" "+ie.IType]++
+	}
+	for _, ie := range missing {
+		key := matchCase(ie.ICaseNum) + " " + ie.IType
+		if candidates := unmatched[key]; len(candidates) == 1 && claimed[key] == 1 {
+			fixes = append(fixes, wordCountFix{ie, candidates[0].WordCount()})
+		}
+	}
+
+	return fixes
+}
+
+// renumberInvoice lowers the No. of the rows below the removed ones by the
+// number of removed rows above them that had their own No.
+func renumberInvoice(f *excelize.File, entries []Entry, removed map[int]bool) (int, error) {
+	var changes int
+	for _, e := range entries {
+		ie := e.(InvoiceEntry)
+		if _, ok := removed[ie.loc.Row]; ok {
+			continue
+		}
+		var moved, above int
+		for row, numbered := range removed {
+			if row < ie.loc.Row {
+				moved++
+				if numbered {
+					above++
+				}
+			}
+		}
+		no, err := strconv.Atoi(ie.rowNum)
+		if above == 0 || err != nil {
+			continue
+		}
+		cell, _ := excelize.CoordinatesToCellName(invoiceColumns.cols[invoiceColumns.field("no")]+1, ie.loc.Row-moved)
+		if err := setCellLike(f, ie.loc.Sheet, cell, strconv.Itoa(no-above)); err != nil {
+			return changes, err
+		}
+		changes++
+		printFixChange("Changed %s: %s → %s (%s)\n", cellRef(ie.loc.Sheet, cell), ie.rowNum, strconv.Itoa(no-above), translate("renumbered after the removed rows"))
+	}
+
+	return changes, nil
+}
+
+// cellIsNumber is true for cells holding a number, which excelize reports
+// as untyped
+func cellIsNumber(f *excelize.File, sheet, cell string) bool {
+	t, err := f.GetCellType(sheet, cell)
+	if err != nil {
+		return false
+	}
+	if t == excelize.CellTypeUnset {
+		value, err := f.GetCellValue(sheet, cell, excelize.Options{RawCellValue: true})
+		return err == nil && value != ""
+	}
+
+	return t == excelize.CellTypeNumber || t == excelize.CellTypeDate
+}
+
+// setCellLike writes value as a number into numeric cells and as text into the others
+func setCellLike(f *excelize.File, sheet, cell, value string) error {
+	if number, err := strconv.ParseFloat(value, 64); err == nil && cellIsNumber(f, sheet, cell) {
+		return f.SetCellValue(sheet, cell, number)
+	}
+
+	return f.SetCellStr(sheet, cell, value)
+}
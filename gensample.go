@@ -0,0 +1,249 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// genSampleCommand writes a matched pair of shuho and invoice workbooks,
+// optionally with injected errors, for tests, demos and CI fixtures
+func genSampleCommand(args []string) int {
+	//from the first of the month, March 31 minus a month is March 3
+	today := time.Now()
+	lastMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+
+	fs := flag.NewFlagSet("gen-sample", flag.ExitOnError)
+	month := fs.String("month", lastMonth.Format("2006-01"), "invoiced month (YYYY-MM)")
+	count := fs.Int("entries", 20, "number of invoiced entries")
+	seed := fs.Int64("seed", 1, "random seed, the same seed gives the same workbooks")
+	duplicates := fs.Int("duplicates", 0, "invoice rows to duplicate")
+	wrongRates := fs.Int("wrong-rates", 0, "invoice rows given the rate of the other type")
+	missing := fs.Int("missing", 0, "shuho entries left out of the invoice")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		printer.Fprintf(out, "\033[1;31mERROR Usage:\033[0m ./verifyshuho gen-sample [OPTIONS] <shuho.xlsx> <invoice.xlsx>\n")
+		fs.PrintDefaults()
+		return 2
+	}
+	if *count < 1 || *duplicates < 0 || *wrongRates < 0 || *missing < 0 {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m --entries must be at least 1, --duplicates, --wrong-rates and --missing at least 0\n")
+		return 2
+	}
+
+	start, err := time.Parse("2006-01", *month)
+	if err != nil {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m Invalid month %s\n", *month)
+		return 2
+	}
+
+	//the shuho dates are written without a year, which is read back as this year or last year
+	if last := start.AddDate(0, 1, -1); thisYearOrLastYear(start).Year() != start.Year() || thisYearOrLastYear(last).Year() != last.Year() {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m The shuho dates of %s would be read as another year, pick a month of the last twelve\n", *month)
+		return 2
+	}
+
+	entries, invoiced, err := writeSamplePair(fs.Arg(0), fs.Arg(1), start, *seed, *count, *duplicates, *wrongRates, *missing)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	showCheckSuccess(printer.Sprintf("Wrote %d shuho entries to %s and %d invoice rows to %s", entries, fs.Arg(0), invoiced, fs.Arg(1)))
+	return 0
+}
+
+// writeSamplePair writes the shuho and invoice of the month and returns how
+// many entries and rows they have
+func writeSamplePair(shuhoName, invoiceName string, start time.Time, seed int64, count, duplicates, wrongRates, missing int) (int, int, error) {
+	r := rand.New(rand.NewSource(seed))
+	entries := sampleShuhoEntries(r, start, count)
+
+	if err := writeSampleShuho(shuhoName, r, start, entries); err != nil {
+		return 0, 0, err
+	}
+
+	invoiced := sampleInvoiceRows(r, entries, duplicates, wrongRates, missing)
+	if err := writeSampleInvoice(invoiceName, start, invoiced); err != nil {
+		return 0, 0, err
+	}
+
+	return len(entries), len(invoiced), nil
+}
+
+var sampleAuthors = []string{"Rubingh", "Tanaka", "Suzuki"}
+
+// sampleShuhoEntries returns count entries spread over the month, sorted by date
+func sampleShuhoEntries(r *rand.Rand, start time.Time, count int) []ShuhoEntry {
+	days := start.AddDate(0, 1, -1).Day()
+	entries := make([]ShuhoEntry, 0, count)
+
+	for i := 0; i < count; i++ {
+		se := ShuhoEntry{
+			SDate:    start.AddDate(0, 0, r.Intn(days)),
+			SCaseNum: fmt.Sprintf("ALP-%04d", 1000+r.Intn(9000)),
+			SAuthor:  sampleAuthors[r.Intn(len(sampleAuthors))],
+		}
+		if r.Intn(3) == 0 {
+			se.SType = "英文チェック"
+			se.SCWordCount = strconv.Itoa(500 + r.Intn(8000))
+		} else {
+			se.SType = "翻訳"
+			se.STWordCount = strconv.Itoa(100 + r.Intn(3000))
+		}
+		entries = append(entries, se)
+	}
+
+	//the invoice is expected in chronological order, and getScopedShuho uses its first and last dates
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].SDate.Before(entries[j].SDate) })
+
+	//pin the period to the whole month
+	if len(entries) > 1 {
+		entries[0].SDate = start
+		entries[len(entries)-1].SDate = start.AddDate(0, 1, -1)
+	}
+
+	return entries
+}
+
+var sampleShuhoHeader = []interface{}{"日付", "案件番号", "種類", "チェック語数", "翻訳語数", "備考", "担当者"}
+
+func writeSampleShuho(name string, r *rand.Rand, start time.Time, entries []ShuhoEntry) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	//first sheet is the template parseShuho skips
+	if err := f.SetSheetName("Sheet1", "template"); err != nil {
+		return err
+	}
+	if err := f.SetSheetRow("template", "A1", &sampleShuhoHeader); err != nil {
+		return err
+	}
+
+	//work from the previous month, outside the invoiced period
+	before := start.AddDate(0, -1, 0)
+	previous := sampleShuhoEntries(r, before, 5)
+	if err := writeSampleShuhoSheet(f, before.Format("2006-01"), previous); err != nil {
+		return err
+	}
+	if err := writeSampleShuhoSheet(f, start.Format("2006-01"), entries); err != nil {
+		return err
+	}
+
+	return f.SaveAs(name)
+}
+
+func writeSampleShuhoSheet(f *excelize.File, sheet string, entries []ShuhoEntry) error {
+	if _, err := f.NewSheet(sheet); err != nil {
+		return err
+	}
+	if err := f.SetSheetRow(sheet, "A1", &sampleShuhoHeader); err != nil {
+		return err
+	}
+
+	for i, se := range entries {
+		row := []interface{}{se.SDate.Format("1/2"), se.SCaseNum, se.SType, se.SCWordCount, se.STWordCount, "", se.SAuthor}
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sampleInvoiceRows turns shuho entries into invoice entries and injects the requested errors
+func sampleInvoiceRows(r *rand.Rand, entries []ShuhoEntry, duplicates, wrongRates, missing int) []InvoiceEntry {
+	rows := make([]InvoiceEntry, 0, len(entries)+duplicates)
+
+	//never drop the first or last entry, they define the period
+	skip := make(map[int]bool)
+	for len(skip) < missing && len(skip) < len(entries)-2 {
+		skip[1+r.Intn(len(entries)-2)] = true
+	}
+
+	for i, se := range entries {
+		if skip[i] {
+			continue
+		}
+		ie := InvoiceEntry{IDate: se.SDate, ICaseNum: se.SCaseNum, IType: se.SType, IWordCount: se.WordCount(), rate: sampleRate(se.SType, false)}
+		rows = append(rows, ie)
+	}
+
+	//distinct rows, so that N wrong rates and duplicates are N findings each
+	wrong := make(map[int]bool)
+	for len(wrong) < wrongRates && len(wrong) < len(rows) {
+		wrong[r.Intn(len(rows))] = true
+	}
+	for n := range wrong {
+		rows[n].rate = sampleRate(rows[n].IType, true)
+	}
+
+	duplicated := make(map[int]bool)
+	for len(duplicated) < duplicates && len(duplicated) < len(rows) {
+		duplicated[r.Intn(len(rows))] = true
+	}
+	withDuplicates := make([]InvoiceEntry, 0, len(rows)+len(duplicated))
+	for n, ie := range rows {
+		withDuplicates = append(withDuplicates, ie)
+		//the copy keeps the right rate, the wrong one stays a single finding
+		if duplicated[n] {
+			ie.rate = sampleRate(ie.IType, false)
+			withDuplicates = append(withDuplicates, ie)
+		}
+	}
+	rows = withDuplicates
+
+	for i := range rows {
+		rows[i].rowNum = strconv.Itoa(i + 1)
+	}
+
+	return rows
+}
+
+func sampleRate(eType string, wrong bool) string {
+	if (eType == "翻訳") != wrong {
+		return "18"
+	}
+
+	return "1.4"
+}
+
+func writeSampleInvoice(name string, start time.Time, rows []InvoiceEntry) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := "Invoice"
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+
+	f.SetCellValue(sheet, "A1", "請求書")
+	f.SetCellValue(sheet, "A2", start.Format("2006年1月分"))
+	header := []interface{}{"No.", "案件番号", "種類", "納品日", "語数", "単価"}
+	if err := f.SetSheetRow(sheet, "A4", &header); err != nil {
+		return err
+	}
+
+	for i, ie := range rows {
+		row := []interface{}{ie.rowNum, ie.ICaseNum, ie.IType, ie.IDate.Format("01-02-06"), ie.IWordCount, ie.rate}
+		cell, err := excelize.CoordinatesToCellName(1, i+5)
+		if err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	return f.SaveAs(name)
+}
@@ -2,14 +2,17 @@ module verifyshuho
 
 go 1.20
 
+require (
+	github.com/richardlehane/mscfb v1.0.4
+	github.com/xuri/excelize/v2 v2.7.1
+	golang.org/x/text v0.9.0
+)
+
 require (
 	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
-	github.com/richardlehane/mscfb v1.0.4 // indirect
 	github.com/richardlehane/msoleps v1.0.3 // indirect
 	github.com/xuri/efp v0.0.0-20220603152613-6918739fd470 // indirect
-	github.com/xuri/excelize/v2 v2.7.1 // indirect
 	github.com/xuri/nfp v0.0.0-20220409054826-5e722a1d9e22 // indirect
 	golang.org/x/crypto v0.8.0 // indirect
 	golang.org/x/net v0.9.0 // indirect
-	golang.org/x/text v0.9.0 // indirect
 )
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// HeaderExpectations are what the invoice header has to say besides the
+// billing period, which always has to be the invoiced month:
+//
+//	"invoice_header": {"translator": "Rubingh", "number_pattern": "^INV-\\d{4}-\\d{2}$", "required": ["number", "issue_date"]}
+type HeaderExpectations struct {
+	Translator    string `json:"translator"`
+	NumberPattern string `json:"number_pattern"`
+	// fields that have to be there: translator, number, period, issue_date
+	Required []string `json:"required"`
+}
+
+// headerField is a value of the invoice header and where it was read
+type headerField struct {
+	value string
+	loc   Location
+}
+
+// invoiceHeader is the block above the invoice entries, by field
+type invoiceHeader map[string]headerField
+
+// the invoice read last, set by parseInvoice for the invoice-header check
+var parsedInvoiceHeader invoiceHeader
+
+// the labels of the header fields, the value is the next cell of the row
+var headerLabels = map[string][]string{
+	"translator": {"氏名", "名前", "翻訳者", "name", "translator"},
+	"number":     {"請求書番号", "請求番号", "invoice no", "invoice no.", "invoice number"},
+	"period":     {"請求期間", "対象期間", "対象月", "period", "billing period"},
+	"issue_date": {"発行日", "請求日", "issue date", "date issued"},
+}
+
+// a period on its own without a label, 2023年6月分
+var headerPeriodRe = regexp.MustCompile(`^\d{4}\s*年\s*\d{1,2}\s*月分$`)
+
+// parseInvoiceHeader reads the labelled cells of the rows above the first entry
+func parseInvoiceHeader(name, sheet string, rows [][]string) invoiceHeader {
+	header := make(invoiceHeader)
+	for i, row := range rows {
+		if mapped := invoiceColumns.apply(row); invoiceDateRe.MatchString(mapped[3]) {
+			break
+		}
+		loc := Location{File: name, Sheet: sheet, Row: i + 1}
+
+		for j, cell := range row {
+			cell = strings.TrimSpace(cell)
+			if headerPeriodRe.MatchString(cell) {
+				header["period"] = headerField{cell, loc}
+				continue
+			}
+			field := headerLabel(cell)
+			if field == "" {
+				continue
+			}
+			for _, value := range row[j+1:] {
+				if value = strings.TrimSpace(value); value != "" {
+					header[field] = headerField{value, loc}
+					break
+				}
+			}
+		}
+	}
+
+	return header
+}
+
+func headerLabel(cell string) string {
+	cell = strings.ToLower(strings.TrimRight(cell, ":： "))
+	for _, field := range sortedKeys(headerLabels) {
+		for _, label := range headerLabels[field] {
+			if cell == label {
+				return field
+			}
+		}
+	}
+
+	return ""
+}
+
+var issueDateLayouts = []string{"2006-01-02", "2006/1/2", "2006年1月2日", "01-02-06", "1/2/2006"}
+
+func parseIssueDate(value string) (time.Time, bool) {
+	for _, layout := range issueDateLayouts {
+		if t, err := time.Parse(layout, strings.ReplaceAll(value, " ", "")); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// checkInvoiceHeader compares the header with the invoiced entries and the
+// invoice_header config: the period is the month of the entries, the issue
+// date isn't before the last entry nor after the next month, and the
+// translator and invoice number are the expected ones
+func checkInvoiceHeader(header invoiceHeader, expect HeaderExpectations, invoice []Entry) []Finding {
+	var findings []Finding
+	add := func(field string, severity Severity, format string, a ...interface{}) {
+		findings = append(findings, Finding{Message: printer.Sprintf(format, a...), Loc: header[field].loc, Severity: severity})
+	}
+
+	for _, field := range expect.Required {
+		if _, ok := header[field]; !ok {
+			add(field, SeverityError, "The invoice header has no %s", translate(strings.ReplaceAll(field, "_", " ")))
+		}
+	}
+	first, last, ok := invoicePeriod(invoice)
+	if !ok {
+		return findings
+	}
+
+	if period, ok := header["period"]; ok {
+		if month, ok := sheetMonth(period.value); !ok {
+			add("period", SeverityWarning, "Unreadable invoice period %q", period.value)
+		} else if month.Year() != first.Year() || month.Month() != first.Month() {
+			add("period", SeverityError, "The invoice header is for %s but the entries are from %s", month.Format("2006-01"), first.Format("2006-01"))
+		}
+	}
+
+	if issued, ok := header["issue_date"]; ok {
+		date, ok := parseIssueDate(issued.value)
+		nextMonthEnd := time.Date(last.Year(), last.Month()+2, 0, 0, 0, 0, 0, time.UTC)
+		switch {
+		case !ok:
+			add("issue_date", SeverityWarning, "Unreadable invoice issue date %q", issued.value)
+		case date.Before(last):
+			add("issue_date", SeverityError, "The invoice is dated %s, before its last entry on %s", date.Format("2006-01-02"), last.Format("2006-01-02"))
+		case date.After(nextMonthEnd):
+			add("issue_date", SeverityError, "The invoice is dated %s, more than a month after its entries", date.Format("2006-01-02"))
+		}
+	}
+
+	if translator, ok := header["translator"]; ok && expect.Translator != "" && translator.value != expect.Translator {
+		add("translator", SeverityError, "The invoice is from %q instead of %q", translator.value, expect.Translator)
+	}
+
+	if number, ok := header["number"]; ok && expect.NumberPattern != "" {
+		if re, err := regexp.Compile(expect.NumberPattern); err == nil && !re.MatchString(number.value) {
+			add("number", SeverityError, "Invoice number %q doesn't match %s", number.value, expect.NumberPattern)
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HistoryRecord is one verification run, appended as a JSON line to the
+// history file given by --history or history_file in the config
+type HistoryRecord struct {
+	RunAt   string `json:"run_at"`
+	Month   string `json:"month"`
+	Invoice string `json:"invoice"`
+	Shuho   string `json:"shuho"`
+	// the translator of the invoice when verified per author, see verifyTeam
+	Author string `json:"author,omitempty"`
+	// yen totals as printed in the summary
+	Translations float64 `json:"translations"`
+	Checks       float64 `json:"checks"`
+	Pretax       float64 `json:"pretax"`
+	Passed       bool    `json:"passed"`
+	// from the invoice header, see the invoice-number check
+	InvoiceNumber string `json:"invoice_number,omitempty"`
+	// the cancelled shuho entries of the month
+	Cancelled *cancelledStats `json:"cancelled,omitempty"`
+	// the invoice entries, for comparing months
+	Entries []HistoryEntry `json:"entries"`
+}
+
+// HistoryEntry is an invoice row of a HistoryRecord
+type HistoryEntry struct {
+	Date  string `json:"date"`
+	Case  string `json:"case"`
+	Type  string `json:"type"`
+	Words string `json:"words"`
+	Rate  string `json:"rate"`
+}
+
+var historyf string
+var history []HistoryRecord
+
+// loadHistory reads every record of path, a file that doesn't exist yet is empty
+func loadHistory(path string) ([]HistoryRecord, error) {
+	var records []HistoryRecord
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024)
+	var line int
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+func appendHistory(path string, record HistoryRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func newHistoryRecord(shuhoName, invoiceName, author string, invoiceEntries []Entry, passed bool) HistoryRecord {
+	translations, checks, pretax := invoiceTotals(invoiceEntries)
+	record := HistoryRecord{
+		RunAt:        now().Format("2006-01-02T15:04:05Z07:00"),
+		Month:        invoiceEntries[0].Date().Format("2006-01"),
+		Invoice:      invoiceName,
+		Shuho:        shuhoName,
+		Author:       author,
+		Translations: translations,
+		Checks:       checks,
+		Pretax:       pretax,
+		Passed:       passed,
+	}
+	if number, ok := parsedInvoiceHeader["number"]; ok {
+		record.InvoiceNumber = number.value
+	}
+	if cancelled := sumCancelled(getScopedShuho(cancelledEntries, invoiceEntries)); cancelled.Entries > 0 {
+		record.Cancelled = &cancelled
+	}
+	for _, e := range invoiceEntries {
+		record.Entries = append(record.Entries, HistoryEntry{e.Date().Format("2006-01-02"), e.CaseNum(), e.Type(), e.WordCount(), e.Rate()})
+	}
+
+	return record
+}
+
+// latestByMonth keeps the last run of every month and author, oldest month
+// first
+func latestByMonth(records []HistoryRecord) []HistoryRecord {
+	months := make(map[string]HistoryRecord)
+	for _, record := range records {
+		months[record.Author+" "+record.Month] = record
+	}
+
+	latest := make([]HistoryRecord, 0, len(months))
+	for _, record := range months {
+		latest = append(latest, record)
+	}
+	sort.Slice(latest, func(i, j int) bool {
+		if latest[i].Month != latest[j].Month {
+			return latest[i].Month < latest[j].Month
+		}
+		return latest[i].Author < latest[j].Author
+	})
+
+	return latest
+}
+
+// historyOf is the records of the author of the shuho entries, the ones of
+// the team's other translators left out. Records without an author are
+// anyone's, and entries of several authors keep every record.
+func historyOf(records []HistoryRecord, shuho []Entry) []HistoryRecord {
+	var author string
+	for _, e := range shuho {
+		se, ok := e.(ShuhoEntry)
+		if !ok || (author != "" && se.SAuthor != author) {
+			return records
+		}
+		author = se.SAuthor
+	}
+	if author == "" {
+		return records
+	}
+
+	var authored []HistoryRecord
+	for _, record := range records {
+		if record.Author == "" || record.Author == author {
+			authored = append(authored, record)
+		}
+	}
+
+	return authored
+}
+
+// annualProjection is the yearly figure printed after the pre-tax total of
+// month, year_to_date averages the months of its calendar or --fiscal-year
+func annualProjection(month string, pretax float64) (float64, bool) {
+	p := config.Projection
+	if p.Disabled {
+		return 0, false
+	}
+	months := p.Months
+	if months == 0 {
+		months = 12
+	}
+	if !p.YearToDate {
+		return pretax * float64(months), true
+	}
+
+	sum, n := pretax, 1
+	for _, record := range latestByMonth(history) {
+		if record.Month != month && sameYear(record.Month, month) {
+			sum += record.Pretax
+			n++
+		}
+	}
+
+	return sum / float64(n) * float64(months), true
+}
+
+// invoiceNumberRe splits an invoice number at its last run of digits, INV-2023-0042
+var invoiceNumberRe = regexp.MustCompile(`^(.*?)(\d+)(\D*)$`)
+
+// checkInvoiceNumber flags an invoice number used for another month in the
+// history, and one that isn't the number of the last recorded invoice plus
+// one: lower is an error, a gap a warning. Numbers whose text around the
+// digits changed, a new year prefix say, aren't compared.
+func checkInvoiceNumber(header invoiceHeader, month string, records []HistoryRecord) []Finding {
+	number, ok := header["number"]
+	if !ok {
+		return nil
+	}
+
+	var findings []Finding
+	var previous HistoryRecord
+	for _, record := range latestByMonth(records) {
+		if record.Month == month || record.InvoiceNumber == "" {
+			continue
+		}
+		if record.InvoiceNumber == number.value {
+			findings = append(findings, Finding{Message: printer.Sprintf("Invoice number %s was already used for %s", number.value, record.Month), Loc: number.loc})
+		}
+		if record.Month < month {
+			previous = record
+		}
+	}
+	if previous.InvoiceNumber == "" || len(findings) > 0 {
+		return findings
+	}
+
+	m, p := invoiceNumberRe.FindStringSubmatch(number.value), invoiceNumberRe.FindStringSubmatch(previous.InvoiceNumber)
+	if m == nil || p == nil || m[1] != p[1] || m[3] != p[3] {
+		return findings
+	}
+	n, _ := strconv.Atoi(m[2])
+	last, _ := strconv.Atoi(p[2])
+	switch {
+	case n <= last:
+		findings = append(findings, Finding{Message: printer.Sprintf("Invoice number %s doesn't follow %s of %s", number.value, previous.InvoiceNumber, previous.Month), Loc: number.loc})
+	case n > last+1:
+		findings = append(findings, Finding{Message: printer.Sprintf("Invoice number %s skips %d numbers after %s of %s", number.value, n-last-1, previous.InvoiceNumber, previous.Month), Loc: number.loc, Severity: SeverityWarning})
+	}
+
+	return findings
+}
+
+// checkInvoicedBefore warns about invoice entries with the signature of an
+// entry the history has on the invoice of another month, billed twice
+func checkInvoicedBefore(invoice []Entry, records []HistoryRecord) []Finding {
+	if len(invoice) == 0 {
+		return nil
+	}
+	month := invoice[0].Date().Format("2006-01")
+
+	billed := make(map[string][]string)
+	for _, record := range latestByMonth(records) {
+		if record.Month == month {
+			continue
+		}
+		for _, e := range record.Entries {
+			signature := fmt.Sprintf("%s %s %s", matchCase(e.Case), e.Type, e.Words)
+			billed[signature] = append(billed[signature], record.Month)
+		}
+	}
+
+	var findings []Finding
+	for _, e := range invoice {
+		if months, ok := billed[e.signature()]; ok {
+			findings = append(findings, Finding{Message: printer.Sprintf("Already invoiced in %s: Row %s", strings.Join(months, ", "), e.String()), Entry: e, Severity: SeverityWarning})
+		}
+	}
+
+	return findings
+}
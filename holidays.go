@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// holidaysf is a file of the user's own days off, vacations and the
+// agency's closing days, on top of the public holidays
+var holidaysf string
+
+// daysOff are the days of the holiday file, date -> note
+var daysOff map[string]string
+
+// olympicHolidays are the days the 2020 and 2021 games moved 海の日,
+// スポーツの日 and 山の日 to, their usual days weren't holidays those years
+var olympicHolidays = map[int]map[string]string{
+	2020: {"2020-07-23": "海の日", "2020-07-24": "スポーツの日", "2020-08-10": "山の日"},
+	2021: {"2021-07-22": "海の日", "2021-07-23": "スポーツの日", "2021-08-08": "山の日"},
+}
+
+// japaneseHolidays are the public holidays of a year from 2020 on, with the
+// substitute holidays for those on a Sunday and the citizens' holidays
+// between two of them. The equinoxes are the usual approximation, good until
+// 2099.
+func japaneseHolidays(year int) map[string]string {
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	//the nth Monday of the month
+	monday := func(month time.Month, n int) time.Time {
+		first := date(month, 1)
+		return first.AddDate(0, 0, (8-int(first.Weekday()))%7+7*(n-1))
+	}
+	equinox := func(base float64) int {
+		y := float64(year - 1980)
+		return int(math.Floor(base + 0.242194*y - math.Floor(y/4)))
+	}
+
+	days := map[time.Time]string{
+		date(time.January, 1):                  "元日",
+		monday(time.January, 2):                "成人の日",
+		date(time.February, 11):                "建国記念の日",
+		date(time.February, 23):                "天皇誕生日",
+		date(time.March, equinox(20.8431)):     "春分の日",
+		date(time.April, 29):                   "昭和の日",
+		date(time.May, 3):                      "憲法記念日",
+		date(time.May, 4):                      "みどりの日",
+		date(time.May, 5):                      "こどもの日",
+		monday(time.September, 3):              "敬老の日",
+		date(time.September, equinox(23.2488)): "秋分の日",
+		date(time.November, 3):                 "文化の日",
+		date(time.November, 23):                "勤労感謝の日",
+	}
+	if moved, ok := olympicHolidays[year]; ok {
+		for day, name := range moved {
+			d, _ := time.Parse("2006-01-02", day)
+			days[d] = name
+		}
+	} else {
+		days[monday(time.July, 3)] = "海の日"
+		days[date(time.August, 11)] = "山の日"
+		days[monday(time.October, 2)] = "スポーツの日"
+	}
+
+	holidays := make(map[string]string)
+	for day, name := range days {
+		holidays[day.Format("2006-01-02")] = name
+	}
+	for day := range days {
+		//a weekday between two holidays is a holiday too
+		if next := day.AddDate(0, 0, 2); days[next] != "" && days[day.AddDate(0, 0, 1)] == "" && day.AddDate(0, 0, 1).Weekday() != time.Sunday {
+			holidays[day.AddDate(0, 0, 1).Format("2006-01-02")] = "国民の休日"
+		}
+		if day.Weekday() != time.Sunday {
+			continue
+		}
+		//the next day that isn't a holiday already
+		substitute := day.AddDate(0, 0, 1)
+		for days[substitute] != "" {
+			substitute = substitute.AddDate(0, 0, 1)
+		}
+		holidays[substitute.Format("2006-01-02")] = "振替休日"
+	}
+
+	return holidays
+}
+
+// loadHolidayFile reads a day or a range of days off per line, with an
+// optional note:
+//
+//	2023-08-14..2023-08-16 お盆休み
+//	2023-12-29 agency closed
+func loadHolidayFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	days := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	var line int
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		span, note, _ := strings.Cut(text, " ")
+		from, to, isRange := strings.Cut(span, "..")
+		if !isRange {
+			to = from
+		}
+		first, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: expected YYYY-MM-DD or YYYY-MM-DD..YYYY-MM-DD", path, line)
+		}
+		last, err := time.Parse("2006-01-02", to)
+		if err != nil || last.Before(first) {
+			return nil, fmt.Errorf("%s:%d: expected YYYY-MM-DD or YYYY-MM-DD..YYYY-MM-DD", path, line)
+		}
+		for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+			days[day.Format("2006-01-02")] = strings.TrimSpace(note)
+		}
+	}
+
+	return days, scanner.Err()
+}
+
+// isDayOff is true for weekends, public holidays and the days of the holiday file
+func isDayOff(day time.Time) bool {
+	_, ok := dayOffName(day)
+
+	return ok
+}
+
+// dayOffName is why day is off, the weekday, the holiday or the note of the
+// holiday file
+func dayOffName(day time.Time) (string, bool) {
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return translate(day.Weekday().String()), true
+	}
+	key := day.Format("2006-01-02")
+	if note, ok := daysOff[key]; ok {
+		if note == "" {
+			note = printer.Sprintf("day off")
+		}
+		return note, true
+	}
+	name, ok := japaneseHolidays(day.Year())[key]
+
+	return name, ok
+}
+
+// checkDaysOff warns about shuho entries dated on a weekend, a public
+// holiday or a day of the holiday file, a mistyped date more often than work
+// on a day off. Without a holiday file the weekends may be work days, the
+// check is left out.
+func checkDaysOff(shuho, invoice []Entry) []Finding {
+	if daysOff == nil {
+		return nil
+	}
+
+	var findings []Finding
+	for _, e := range getScopedShuho(shuho, invoice) {
+		if name, ok := dayOffName(e.Date()); ok {
+			findings = append(findings, Finding{
+				Message:  printer.Sprintf("Shuho entry on a day off (%s) at %s: %s", name, e.Location(), e.String()),
+				Entry:    e,
+				Severity: SeverityWarning,
+				Field:    "date",
+			})
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// messages holds the translations of user-facing strings, keyed by the
+// English text or format string printed without a --lang
+var messages = catalog.NewBuilder()
+
+// printer formats every user-facing string. Without --lang it is
+// undetermined: English messages, and types keep the Japanese labels of the
+// workbooks. Matching always uses the raw cell values.
+var printer = message.NewPrinter(language.Und, message.Catalog(messages))
+
+// outputLanguage is the --lang flag
+type outputLanguage string
+
+func (l *outputLanguage) String() string {
+	return string(*l)
+}
+
+func (l *outputLanguage) Set(value string) error {
+	tag, ok := outputLanguages[value]
+	if !ok {
+		return fmt.Errorf("unknown language %q, use en or ja", value)
+	}
+	*l = outputLanguage(value)
+	printer = message.NewPrinter(tag, message.Catalog(messages))
+
+	return nil
+}
+
+var outputLanguages = map[string]language.Tag{
+	"en": language.English,
+	"ja": language.Japanese,
+}
+
+var langf outputLanguage
+
+// English labels of the work types, the only strings the en catalog translates
+var englishMessages = map[string]string{
+	"翻訳":     "Translation",
+	"英文チェック": "English check",
+}
+
+var japaneseMessages = map[string]string{
+	"------------------------\n": "------------------------\n",
+	"Verify Shuho and Invoice\n": "週報と請求書の照合\n",
+	"\033[1;31mERROR Usage:\033[0m ./verifyshuho [OPTIONS] <Shuho.xlsx> <Invoice.xlsx>\n":                                                                                   "\033[1;31mERROR 使い方:\033[0m ./verifyshuho [オプション] <週報.xlsx> <請求書.xlsx>\n",
+	"Either side can be an https:// or s3:// URL, s3 uses the AWS_ credentials and region from the environment\n":                                                           "どちらも https:// や s3:// の URL でも可、s3 は環境変数の AWS_ 認証情報とリージョンを使用\n",
+	"SharePoint and OneDrive share links are fetched with GRAPH_ACCESS_TOKEN or the AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET app\n":                         "SharePoint や OneDrive の共有リンクは GRAPH_ACCESS_TOKEN または AZURE_TENANT_ID、AZURE_CLIENT_ID、AZURE_CLIENT_SECRET のアプリで取得\n",
+	"The invoice can be a .zip of invoices, each is verified against the shuho\n":                                                                                           "請求書は複数の請求書の .zip でも可、それぞれを週報と照合\n",
+	"The invoice can be a directory of the team's invoices, each is verified against the shuho rows of its author\n":                                                        "請求書はチームの請求書のディレクトリでも可、それぞれを担当者の週報の行と照合\n",
+	"Either side can be a .json or .csv of entries written by the export command\n":                                                                                         "どちらも export コマンドで書き出した明細の .json や .csv でも可\n",
+	"Workbooks can also be legacy Excel 97-2003 .xls or OpenDocument .ods files, - reads one from stdin\n":                                                                  "旧形式の Excel 97-2003 .xls と OpenDocument .ods も読める、- は標準入力から読む\n",
+	"A name.schema.json next to a workbook gives the columns, header, date formats and skipped rows of other layouts\n":                                                     "ワークブックの隣の name.schema.json で別のレイアウトの列、見出し、日付形式、読み飛ばす行を指定できる\n",
+	"\033[1;31mERROR:\033[0m --precision %d, use 0 to 6 decimals\n":                                                                                                         "\033[1;31mERROR:\033[0m --precision %d、小数点以下は 0 から 6 桁\n",
+	"Unchanged since the successful run at %s, pre-tax total %s\n":                                                                                                          "%s の合格から変更なし、税引前合計 %s\n",
+	"\033[1;31mERROR:\033[0m Reading the state file: %s\n":                                                                                                                  "\033[1;31mERROR:\033[0m 状態ファイルの読み込み: %s\n",
+	"\033[1;31mERROR:\033[0m Writing the state file: %s\n":                                                                                                                  "\033[1;31mERROR:\033[0m 状態ファイルの書き込み: %s\n",
+	"\033[1;31mERROR:\033[0m Writing the audit log: %s\n":                                                                                                                   "\033[1;31mERROR:\033[0m 監査ログの書き込み: %s\n",
+	"./verifyshuho normalize <in.xlsx> <out.xlsx> write a cleaned copy of a workbook\n":                                                                                     "./verifyshuho normalize <入力.xlsx> <出力.xlsx> ブックを整えたコピーを書く\n",
+	"./verifyshuho anonymize <in.xlsx> <out.xlsx> [<in.xlsx> <out.xlsx>...] replace case numbers, authors and rates with synthetic values, matching across the workbooks\n": "./verifyshuho anonymize <入力.xlsx> <出力.xlsx> [<入力.xlsx> <出力.xlsx>...] 案件番号・担当者・単価を架空の値に置き換える、ブック間で対応は保たれる\n",
+	"./verifyshuho gen-sample <shuho.xlsx> <invoice.xlsx> write a matched pair of sample workbooks\n":                                                                       "./verifyshuho gen-sample <週報.xlsx> <請求書.xlsx> 対になるサンプルのブックを書く\n",
+	"./verifyshuho export [--format json|csv] <workbook.xlsx> print the parsed entries\n":                                                                                   "./verifyshuho export [--format json|csv] <workbook.xlsx> 読み取った明細を出力\n",
+	"./verifyshuho stats [--json] <Shuho.xlsx> <Invoice.xlsx> print run metrics instead of the report\n":                                                                    "./verifyshuho stats [--json] <Shuho.xlsx> <Invoice.xlsx> レポートの代わりに実行の統計を表示\n",
+	"./verifyshuho dashboard --history history.jsonl serve a page of the recorded months on localhost\n":                                                                    "./verifyshuho dashboard --history history.jsonl 記録した月の一覧をローカルで表示\n",
+	"./verifyshuho report --quarter 2024Q2 [--fiscal-year] --history history.jsonl sum up a quarter and compare it with the one before\n":                                   "./verifyshuho report --quarter 2024Q2 [--fiscal-year] --history history.jsonl 四半期の合計を前の四半期と比べて表示\n",
+	"./verifyshuho tax-export --year 2024 [--fiscal-year] --history history.jsonl CSV of the monthly income and withholding for tax-filing software\n":                      "./verifyshuho tax-export --year 2024 [--fiscal-year] --history history.jsonl 確定申告ソフト用に月別の売上と源泉徴収税額を CSV で書き出す\n",
+	"./verifyshuho reconcile [--payer name] --history history.jsonl <statement.csv> match the months to the payments of a bank statement\n":                                 "./verifyshuho reconcile [--payer name] --history history.jsonl <statement.csv> 銀行の明細の入金と各月を照合\n",
+	"Serving the dashboard for %s on %s\n":                                                                                                                                  "%s のダッシュボードを %s で表示中\n",
+	"./verifyshuho daemon [--socket path] answer verifications on a unix socket, keeping the workbooks in memory\n":                                                         "./verifyshuho daemon [--socket path] ワークブックをメモリに保持し、unixソケットで照合に応答\n",
+	"./verifyshuho ask verify <Shuho.xlsx> <Invoice.xlsx> | ask case <Shuho.xlsx> <case number> query the daemon\n":                                                         "./verifyshuho ask verify <Shuho.xlsx> <Invoice.xlsx> | ask case <Shuho.xlsx> <案件番号> デーモンに問い合わせ\n",
+	"%s at %s": "%s（%s）",
+	"\033[1;31mERROR:\033[0m Unknown hyperlinks mode %q, use auto, always or never\n": "\033[1;31mERROR:\033[0m 不明なハイパーリンクのモード %q です。auto、always、never のいずれかを指定してください\n",
+	"\033[1;31mERROR:\033[0m Unknown --fix %q, use shuho or invoice\n":                "\033[1;31mERROR:\033[0m 不明な --fix %q です。shuho か invoice を指定してください\n",
+	"\033[1;31mERROR:\033[0m Fixing the shuho: %s\n":                                  "\033[1;31mERROR:\033[0m 週報の修正: %s\n",
+	"\033[1;33mSKIPPED:\033[0m Unknown type %q, add by hand: Row %s\n":                "\033[1;33mスキップ:\033[0m 不明な種類 %q のため手動で追加してください: 行 %s\n",
+	"\033[1;33mSKIPPED:\033[0m No sheet for %s in the shuho, add by hand: Row %s\n":   "\033[1;33mスキップ:\033[0m 週報に %s のシートがないため手動で追加してください: 行 %s\n",
+	"Added Row %s at %s\n": "行 %s を %s に追加\n",
+	"Nothing to add\n":     "追加する項目はありません\n",
+	"Wrote the fixed shuho with %d added entries to %s\n":           "%d 件を追加した週報を %s に書き出しました\n",
+	"added from invoice row %s":                                     "請求書の行 %s から追加",
+	"\033[1;31mERROR:\033[0m Fixing the invoice: %s\n":              "\033[1;31mERROR:\033[0m 請求書の修正: %s\n",
+	"Changed %s: %s → %s (%s)\n":                                    "%s を変更: %s → %s（%s）\n",
+	"Removed duplicate row %s\n":                                    "重複行 %s を削除\n",
+	"Nothing to change\n":                                           "変更する項目はありません\n",
+	"Wrote the fixed invoice with %d changes to %s\n":               "%d 件を変更した請求書を %s に書き出しました\n",
+	"rate of the type":                                              "種類の単価",
+	"word count in the shuho":                                       "週報の語数",
+	"renumbered after the removed rows":                             "削除した行の後の番号を振り直し",
+	"\033[1;31mERROR:\033[0m --dry-run previews --fix, give both\n": "\033[1;31mERROR:\033[0m --dry-run は --fix のプレビューです。両方を指定してください\n",
+	"Dry run, %s not written\n":                                     "ドライラン: %s は書き出していません\n",
+	"new row":                                                       "新しい行",
+	"Backed up %s to %s\n":                                          "%s を %s にバックアップしました\n",
+	"No fixes to undo in %s\n":                                      "%s に元に戻せる修正はありません\n",
+	"\033[1;31mERROR:\033[0m %s changed since the fix of %s, undo with --force to lose the changes\n": "\033[1;31mERROR:\033[0m %s は %s の修正の後に変更されています。変更を破棄するには --force を付けてください\n",
+	"\033[1;31mERROR:\033[0m The fix of %s overwrote %s without a backup\n":                           "\033[1;31mERROR:\033[0m %s の修正はバックアップなしで %s を上書きしました\n",
+	"Undid the %s fix of %s on %s:\n": "%s の修正（%s）を %s で取り消しました:\n",
+	"\033[1;31mERROR:\033[0m %s is gone since the fix of %s, undo with --force to drop the fix\n":         "\033[1;31mERROR:\033[0m %s は %s の修正の後に削除されています。修正の記録を外すには --force を付けてください\n",
+	"%s the fix created is gone already\n":                                                                "修正で作成された %s はすでにありません\n",
+	"Dropped the fix of the missing %s\n":                                                                 "見つからない %s の修正の記録を外しました\n",
+	"Removed %s, the fix created it\n":                                                                    "修正で作成された %s を削除しました\n",
+	"Restored %s from %s\n":                                                                               "%s を %s から復元しました\n",
+	"Added the sheet %s with %d business days to %s":                                                      "シート %s（営業日 %d 日）を %s に追加しました",
+	"\033[1;31mERROR Usage:\033[0m ./verifyshuho normalize [--kind shuho|invoice] <in.xlsx> <out.xlsx>\n": "\033[1;31mERROR 使い方:\033[0m ./verifyshuho normalize [--kind shuho|invoice] <入力.xlsx> <出力.xlsx>\n",
+	"\033[1;31mERROR Usage:\033[0m ./verifyshuho anonymize [--kind shuho|invoice] <in.xlsx> <out.xlsx> [<in.xlsx> <out.xlsx>...]\n": "\033[1;31mERROR 使い方:\033[0m ./verifyshuho anonymize [--kind shuho|invoice] <入力.xlsx> <出力.xlsx> [<入力.xlsx> <出力.xlsx>...]\n",
+	"\033[1;31mERROR Usage:\033[0m ./verifyshuho gen-sample [OPTIONS] <shuho.xlsx> <invoice.xlsx>\n":                                "\033[1;31mERROR 使い方:\033[0m ./verifyshuho gen-sample [オプション] <週報.xlsx> <請求書.xlsx>\n",
+	"\033[1;31mERROR Usage:\033[0m ./verifyshuho export [--kind shuho|invoice] [--format json|csv] [-o file] <workbook.xlsx>\n":     "\033[1;31mERROR 使い方:\033[0m ./verifyshuho export [--kind shuho|invoice] [--format json|csv] [-o ファイル] <ブック.xlsx>\n",
+	"\033[1;31mERROR:\033[0m Unknown workbook kind %q\n":                                                                            "\033[1;31mERROR:\033[0m 不明なブックの種類 %q です。shuho か invoice を指定してください\n",
+	"Normalized %s workbook, %d cells changed -> %s":                                                                                "%s のブックを整形しました。変更したセル %d 個 -> %s",
+	"Anonymized %s workbook, %d cells changed -> %s":                                                                                "%s のブックを匿名化しました。変更したセル %d 個 -> %s",
+	"\033[1;31mERROR:\033[0m --entries must be at least 1, --duplicates, --wrong-rates and --missing at least 0\n":                  "\033[1;31mERROR:\033[0m --entries は 1 以上、--duplicates、--wrong-rates、--missing は 0 以上を指定してください\n",
+	"\033[1;31mERROR:\033[0m The shuho dates of %s would be read as another year, pick a month of the last twelve\n":                "\033[1;31mERROR:\033[0m %s の週報の日付は別の年として読まれます。過去12か月の月を指定してください\n",
+	"Wrote %d shuho entries to %s and %d invoice rows to %s":                                                                        "週報の項目 %d 件を %s に、請求書の行 %d 件を %s に書き出しました",
+	"\033[1;33mISSUE:\033[0m %s (%s): %s":                                                                                           "\033[1;33m問題:\033[0m %s (%s): %s",
+	"\033[1;31mERROR:\033[0m Invalid month %s\n":                                                                                    "\033[1;31mERROR:\033[0m 無効な月 %s\n",
+	"The invoice header has no %s":                                                                                                  "請求書のヘッダーに%sがない",
+	"Unreadable invoice period %q":                                                                                                  "請求書の期間 %q を読み取れない",
+	"The invoice header is for %s but the entries are from %s":                                                                      "請求書のヘッダーは %s だが項目は %s のもの",
+	"Unreadable invoice issue date %q":                                                                                              "請求書の発行日 %q を読み取れない",
+	"The invoice is dated %s, before its last entry on %s":                                                                          "請求書の発行日 %s が最後の項目 %s より前",
+	"The invoice is dated %s, more than a month after its entries":                                                                  "請求書の発行日 %s が項目から1か月以上後",
+	"The invoice is from %q instead of %q":                                                                                          "請求書の名義が %q で、%q ではない",
+	"Invoice number %q doesn't match %s":                                                                                            "請求書番号 %q が %s に一致しない",
+	"translator":                                                                                                                    "氏名",
+	"number":                                                                                                                        "請求書番号",
+	"period":                                                                                                                        "請求期間",
+	"issue date":                                                                                                                    "発行日",
+	"Invoice number %s was already used for %s":                                                                                     "請求書番号 %s は %s で使用済み",
+	"Invoice number %s doesn't follow %s of %s":                                                                                     "請求書番号 %s が %s（%s）の続きになっていない",
+	"Invoice number %s skips %d numbers after %s of %s":                                                                             "請求書番号 %s は %d 番飛んでいる（前回 %s、%s）",
+	"No PO number (Row %s)":                                                                                                         "PO番号がない（行 %s）",
+	"No PO number for case %s in the shuho (Row %s)":                                                                                "週報に案件 %s のPO番号がない（行 %s）",
+	"PO number %s isn't the shuho's %s (Row %s)":                                                                                    "PO番号 %s が週報の %s と違う（行 %s）",
+	"%s %s totals %d words in %d invoice rows but %d words in %d shuho rows":                                                        "%s %s の合計は請求書 %d 語（%d 行）、週報 %d 語（%d 行）",
+	"Split delivery of %d Shuho Entries (%s): Row %s":                                                                               "週報 %d 件の分割納品（%s）: 行 %s",
+	"Already invoiced in %s: Row %s":                                                                                                "%s に請求済み: 行 %s",
+	"Cancelled entry invoiced (cancelled at %s): Row %s":                                                                            "キャンセル済みの項目が請求されている（%s）: 行 %s",
+	"Cancelled Entries: %d\n":                                                                                                       "キャンセル済み項目: %d\n",
+	"\n** Cancelled Entries: ":                                                                                                      "\n** キャンセル済みの項目: ",
+	"Cancelled in the period: %d entries, %d translation words and %d check words unpaid\n":                                         "期間内のキャンセル: %d 件、翻訳 %d 語、英文チェック %d 語が未払い\n",
+	"Not invoiced after %d days: %s":                                                                                                "%d 日経っても未請求: %s",
+	"\033[1;31mUNBILLED:\033[0m %s at %s\n":                                                                                         "\033[1;31m未請求:\033[0m %s（%s）\n",
+	"\n%d of %d shuho entries are on none of the %d invoices\n":                                                                     "\n週報 %d 件（全 %d 件）が %d 件の請求書のどれにもない\n",
+	"All %d shuho entries are on one of the %d invoices":                                                                            "週報の全 %d 件が %d 件の請求書のどれかにある",
+	"Rate %q is not a number (Row %s)":                                                                                              "単価 %q が数値でない（行 %s）",
+	"Rate %s is not in the rate table, nearest is %s (Row %s)":                                                                      "単価 %s が単価表にない、最も近いのは %s（行 %s）",
+	"Rate %s is not the rate %s of %s on %s (Row %s)":                                                                               "単価 %s が %s と違う（%s、%s）（行 %s）",
+	"Rate %s is not the flat %s of %s up to %d words (Row %s)":                                                                      "単価 %s が定額 %s（%s、%d 語まで）と違う（行 %s）",
+	"Rate %s is not the rate %s of %s for %s (Row %s)":                                                                              "単価 %s が %s（%s、%s）と違う（行 %s）",
+	"%d: %s → %s, subtotal %s\n":                                                                                                    "%d: %s → %s、小計 %s\n",
+	"Shares this month:":                                                                                                            "今月の割合:",
+	"Shares in %d so far:":                                                                                                          "%d 年の累計の割合:",
+	"Shares in FY%d so far:":                                                                                                        "%d 年度の累計の割合:",
+	"other types":                                                                                                                   "その他の種類",
+	"  %s: revenue %.1f%%, words %.1f%%, entries %.1f%%\n":                                                                          "  %s: 売上の %.1f%%、語数の %.1f%%、件数の %.1f%%\n",
+	"Word counts this month:\n":                                                                                                     "今月の語数:\n",
+	"  %s: min %.0f, median %.0f, mean %.0f, p90 %.0f, max %.0f\n":                                                                  "  %s: 最小 %.0f、中央値 %.0f、平均 %.0f、90パーセンタイル %.0f、最大 %.0f\n",
+	"Word count %s is far from the usual %.0f of %s: Row %s":                                                                        "語数 %s が通常の %.0f（%s）からかけ離れている: 行 %s",
+	"Word count %q is not a positive number: Row %s":                                                                                "語数 %q が正の数でない: 行 %s",
+	"Word count %q is not a positive number at %s: %s":                                                                              "語数 %q が正の数でない（%s）: %s",
+	"%d words of %s on %s, more than the daily capacity of %d: %s":                                                                  "%d 語の%s（%s）が 1 日の上限 %d を超えている: %s",
+	"\n** Per day: ":                         "\n** 日別: ",
+	"Day":                                    "日付",
+	"%d words on %s but no time tracked: %s": "%d 語（%s）なのに記録された作業時間がない: %s",
+	"Shuho entry on a day off (%s) at %s: %s": "休日（%s）の週報項目 %s: %s",
+	"day off":         "休み",
+	"Saturday":        "土曜日",
+	"Sunday":          "日曜日",
+	"%s %s, %s words": "%s %s、%s 語",
+	"\033[1;31mERROR:\033[0m Writing the calendar: %s\n": "\033[1;31mERROR:\033[0m カレンダーの書き出し: %s\n",
+	"Wrote the calendar to %s\n":                         "カレンダーを %s に書き出しました\n",
+	"%s (%s to %s), %d of 3 months recorded\n":           "%s（%s〜%s）、3 か月中 %d か月の記録\n",
+	"Type":                    "種類",
+	"Words":                   "語数",
+	"Amount":                  "金額",
+	"Pre-tax total: %s\n":     "税抜合計: %s\n",
+	"No months of %s in %s\n": "%s の月が %s にない\n",
+	"No months of the previous quarter %s recorded\n":                             "前の四半期 %s の記録なし\n",
+	"Previous quarter %s: %s\n":                                                   "前の四半期 %s: %s\n",
+	"Previous quarter %s: %s (%+.1f%%)\n":                                         "前の四半期 %s: %s（%+.1f%%）\n",
+	"\033[1;32mPAID:\033[0m %s %s on %s (expected %s)\n":                          "\033[1;32m入金済み:\033[0m %s %s（%s、予定 %s）\n",
+	"\033[1;31mSHORT:\033[0m %s %s paid by %s, %s short of %s\n":                  "\033[1;31m不足:\033[0m %s %s（%s まで）、%s 不足（予定 %s）\n",
+	"\033[1;31mUNPAID:\033[0m %s expected %s\n":                                   "\033[1;31m未入金:\033[0m %s 予定 %s\n",
+	"\033[1;33mPENDING:\033[0m %s expected %s after the end of the statement\n":   "\033[1;33m未確定:\033[0m %s 予定 %s、明細の期間後\n",
+	"Author %q is not in the authors list at %s: %s":                              "担当者 %q が担当者リストにない（%s）: %s",
+	"Author %q is not in the authors list, %q misspelled? At %s: %s":              "担当者 %q が担当者リストにない、%q の誤記？（%s）: %s",
+	"\033[1;31mERROR:\033[0m None of the shuho authors is the translator of %s\n": "\033[1;31mERROR:\033[0m %s の翻訳者が週報の担当者にいません\n",
+	"\n** Per translator: ":                                                       "\n** 翻訳者別: ",
+	"none":                                                                        "なし",
+	"no invoice":                                                                  "請求書なし",
+	"Listening on %s\n":                                                           "%s で待ち受け中\n",
+	"./verifyshuho new-sheet [--month YYYY-MM] [--holidays days-off.txt] <shuho.xlsx> add next month's sheet with its business days from the template sheet\n": "./verifyshuho new-sheet [--month YYYY-MM] [--holidays days-off.txt] <shuho.xlsx> テンプレートのシートから来月のシートを営業日付きで追加\n",
+	"./verifyshuho audit-unbilled <shuho.xlsx> <invoice.xlsx>... list the entries of every shuho sheet on none of the invoices\n":                              "./verifyshuho audit-unbilled <shuho.xlsx> <invoice.xlsx>... 週報の全シートでどの請求書にもない項目を一覧表示\n",
+	"./verifyshuho undo [--force] restore the workbook the last --fix wrote\n":                                                                                 "./verifyshuho undo [--force] 最後の --fix が書き込んだワークブックを元に戻す\n",
+	"./verifyshuho version print the version, commit and build date\n":                                                                                         "./verifyshuho version バージョン、コミット、ビルド日を表示\n",
+	"\033[1;31mERROR:\033[0m Only one workbook can be read from stdin\n":                                                                                       "\033[1;31mERROR:\033[0m 標準入力から読めるブックは一つだけ\n",
+	"Empty Shuho or Invoice Entries variable\n":                                                                                                                "週報または請求書の項目がない\n",
+	"\n\033[1;31mFAILED:\033[0m rows could not be parsed\n":                                                                                                    "\n\033[1;31m失敗:\033[0m 読み込めない行がある\n",
+	"\033[1;31mERROR:\033[0m Copying the totals: %s\n":                                                                                                         "\033[1;31mERROR:\033[0m 合計のコピー: %s\n",
+	"\033[1;31mERROR:\033[0m %s report: %s\n":                                                                                                                  "\033[1;31mERROR:\033[0m %s レポート: %s\n",
+	"Copied the totals to the clipboard":                                                                                                                       "合計をクリップボードにコピーした",
+
+	"Invoice Entries: %d\n":                               "請求書項目: %d\n",
+	"Shuho Entries: %d\n":                                 "週報項目: %d\n",
+	"Total Translations: \033[1;36m%d\033[0m\n":           "翻訳件数: \033[1;36m%d\033[0m\n",
+	"Total Checks: %d\n":                                  "英文チェック件数: %d\n",
+	"Unknown Types: \033[1;33m%d\033[0m\n":                "不明な種類: \033[1;33m%d\033[0m\n",
+	"Ignored %d known exceptions\n":                       "承認済みの例外 %d 件を無視\n",
+	"Total for translations: \t%s\n":                      "翻訳の金額: \t\t%s\n",
+	"Total for Checks:     \t\t%s\n":                      "英文チェックの金額: \t%s\n",
+	"\033[1;31mPre-T Total: \t\t\t%s\033[0m (%s /YR)\n":   "\033[1;31m税引前合計: \t\t%s\033[0m (年 %s)\n",
+	"\033[1;31mPre-T Total: \t\t\t%s\033[0m\n":            "\033[1;31m税引前合計: \t\t%s\033[0m\n",
+	"\033[1;31mERROR:\033[0m Recording the run: %s\n":     "\033[1;31mERROR:\033[0m 実行の記録: %s\n",
+	"\n\033[1;31mFAILED:\033[0m %d errors, %d warnings\n": "\n\033[1;31m失敗:\033[0m エラー %d件、警告 %d件\n",
+	"\n** All Checks: ":                                   "\n** 全英文チェック: ",
+	"\n** All Translations: ":                             "\n** 全翻訳: ",
+	"\n** All Invoices: ":                                 "\n** 請求書の全項目: ",
+	"\n** Per author: ":                                   "\n** 担当者別: ",
+	"\n** Words per day: ":                                "\n** 日別語数: ",
+	"\n** Words per week: ":                               "\n** 週別語数: ",
+	"# translation words, + check words\n":                "# 翻訳語数、+ 英文チェック語数\n",
+	"Mon":                                                 "月",
+	"Tue":                                                 "火",
+	"Wed":                                                 "水",
+	"Thu":                                                 "木",
+	"Fri":                                                 "金",
+	"Sat":                                                 "土",
+	"Sun":                                                 "日",
+	"Pre-tax total per month":                             "月別の税引前合計",
+	"Pre-tax total":                                       "税引前合計",
+	"Translations and checks per month":                   "月別の翻訳と英文チェック",
+	"Translations":                                        "翻訳",
+	"Checks":                                              "英文チェック",
+	"Invoiced words per day":                              "日別の請求語数",
+	"max %.0f":                                            "最大 %.0f",
+	"Wrote the charts to %s\n":                            "グラフを %s に書き出しました\n",
+	"\033[1;31mERROR:\033[0m Writing the charts: %s\n":                  "\033[1;31mERROR:\033[0m グラフの書き出し: %s\n",
+	"\033[1;31mERROR:\033[0m Unknown chart format %q, use svg or png\n": "\033[1;31mERROR:\033[0m 不明なグラフ形式 %q、svg か png を指定\n",
+	"\n** Per invoice: ":      "\n** 請求書別: ",
+	"** Fixing the shuho: ":   "** 週報の修正: ",
+	"** Fixing the invoice: ": "** 請求書の修正: ",
+	"Invoice":                 "請求書",
+	"Errors":                  "エラー",
+	"Warnings":                "警告",
+	"Result":                  "結果",
+	"passed":                  "合格",
+	"failed":                  "不合格",
+	"\033[1;31mERROR:\033[0m No invoices in %s\n": "\033[1;31mERROR:\033[0m %s に請求書がありません\n",
+	"Author":                 "担当者",
+	"Entries":                "件数",
+	"Translation words":      "翻訳語数",
+	"Check words":            "チェック語数",
+	"Not invoiced":           "請求漏れ",
+	"\n** Per shuho sheet: ": "\n** 週報シート別: ",
+	"Sheet":                  "シート",
+	"Total":                  "合計",
+	"\n** All Shuhos: ":      "\n** 週報の全項目: ",
+	"** Parse issues: ":      "** 読み込みの問題: ",
+
+	"Invoice rates are correct":                        "請求書の単価が正しい",
+	"No Duplicate Invoice Entries":                     "請求書に重複なし",
+	"Invoice header matches the invoiced month":        "請求書のヘッダーが請求月と一致",
+	"Invoice number follows the last recorded invoice": "請求書番号が前回の請求書の続き",
+	"Invoice PO numbers match the shuho":               "請求書のPO番号が週報と一致",
+	"Word counts per case and type match":              "案件と種類ごとの語数が一致",
+	"No entries invoiced in another month":             "他の月に請求済みの項目がない",
+	"No cancelled entries on the invoice":              "キャンセル済みの項目が請求書にない",
+	"No shuho entries overdue for invoicing":           "請求が遅れている週報項目がない",
+	"Invoice rates are in the rate table":              "請求書の単価が単価表にある",
+	"No word counts far outside the history":           "履歴からかけ離れた語数なし",
+	"All word counts are positive numbers":             "語数はすべて正の数",
+	"No shuho day over the daily capacity":             "1 日の上限を超えた週報の日なし",
+	"No shuho day without tracked time":                "作業時間の記録がない週報の日なし",
+	"No shuho entries on a day off":                    "休日の週報項目なし",
+	"All shuho authors are in the authors list":        "週報の担当者はすべて担当者リストにある",
+	"All Invoice Entries are in the Shuho":             "請求書の全項目が週報にある",
+	"All Shuho Entries are in the Invoice":             "週報の全項目が請求書にある",
+	"No Shuho rows with both word counts":              "週報に語数が両方入った行なし",
+	"All entries have a known type":                    "全項目の種類が既知",
+	"Invoice entries are in chronological order":       "請求書の項目が日付順",
+	"Invoice rows are numbered 1 to N":                 "請求書の行番号が 1 から N まで連続",
+	"All rows parsed cleanly":                          "全行を読み込めた",
+	"Rule %s holds (%s)":                               "ルール %s を満たす (%s)",
+
+	"Rate is incorrect (Row %s)":                                   "単価が正しくない (行 %s)",
+	"Duplicate entry (Row %s)":                                     "重複した項目 (行 %s)",
+	"Word count off by %d from the Shuho: Row %s":                  "語数が週報と %d ずれている: 行 %s",
+	"Invoice Entry Not in Shuho: Row %s":                           "週報にない請求書項目: 行 %s",
+	"Shuho Entry Not in Invoice: %s":                               "請求書にない週報項目: %s",
+	"Unknown type %q at %s: %s":                                    "不明な種類 %q (%s): %s",
+	"Both word counts filled (check %s, translation %s) at %s: %s": "語数が両方入っている (チェック %s、翻訳 %s) %s: %s",
+	"Invoice entry dated before the row above it (%s): Row %s":     "請求書の項目が上の行 (%s) より前の日付: 行 %s",
+	"Invoice row number %q is not a number: Row %s":                "請求書の行番号 %q が数字でない: 行 %s",
+	"Invoice row numbers skip from %d to %d: Row %s":               "請求書の行番号が %d から %d に飛んでいる: 行 %s",
+	"Invoice row number %d repeats or goes back after %d: Row %s":  "請求書の行番号 %d が %d の後で重複または逆行している: 行 %s",
+	"Rule %s broken (%s)":                                          "ルール %s 違反 (%s)",
+
+	"invalid date":           "日付が正しくない",
+	"incomplete row":         "不完全な行",
+	"empty case number":      "案件番号がない",
+	"missing type or author": "種類か担当者がない",
+	"no word count":          "語数がない",
+}
+
+func init() {
+	for key, msg := range englishMessages {
+		messages.SetString(language.English, key, msg)
+	}
+	for key, msg := range japaneseMessages {
+		messages.SetString(language.Japanese, key, msg)
+	}
+}
+
+// translate looks up a string that isn't a format, such as a check name
+func translate(s string) string {
+	if strings.Contains(s, "%") {
+		return s
+	}
+
+	return printer.Sprintf(s)
+}
+
+// typeLabel is how a work type is shown, only --lang en translates it
+func typeLabel(eType string) string {
+	return translate(eType)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// exportICalf is where --export-ical writes the shuho entries as events
+var exportICalf string
+
+// writeICal writes an all-day event per entry. The UIDs only depend on the
+// entry, so importing the file again updates the events instead of adding
+// them twice.
+func writeICal(w io.Writer, entries []Entry) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//verifyshuho//" + version + "//EN",
+		"CALSCALE:GREGORIAN",
+	}
+	stamp := now().UTC().Format("20060102T150405Z")
+	for _, e := range entries {
+		uid := sha256.Sum256([]byte(e.signature() + " " + e.Date().Format("2006-01-02")))
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:%x@verifyshuho", uid[:12]),
+			"DTSTAMP:"+stamp,
+			"DTSTART;VALUE=DATE:"+e.Date().Format("20060102"),
+			"DTEND;VALUE=DATE:"+e.Date().AddDate(0, 0, 1).Format("20060102"),
+			"SUMMARY:"+icalText(printer.Sprintf("%s %s, %s words", e.CaseNum(), typeLabel(e.Type()), e.WordCount())),
+			"DESCRIPTION:"+icalText(e.String()),
+			"TRANSP:TRANSPARENT",
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, foldICalLine(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeICalFile(path string, entries []Entry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeICal(f, entries); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// icalText escapes the characters RFC 5545 reserves in text values
+func icalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// foldICalLine breaks lines longer than 75 octets, continuation lines start
+// with a space. Japanese text is folded between characters, never inside one.
+func foldICalLine(line string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		if n := utf8.RuneLen(r); width+n > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += utf8.RuneLen(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// IgnoreRule is an accepted discrepancy, one per line of the ignore file:
+//
+//	# check id      case number   month
+//	shuho-in-invoice ALP-1234     2023-06   invoiced by the agency directly
+//	invoice-header   Invoice!A2   *         the agency's own header
+//
+// "*" matches any check id, case number or month, text after the month is a
+// note. Instead of a case number the row of the finding can be given as its
+// cell, which is how findings about no entry, like the invoice header, are
+// ignored. Those have no month, only "*" matches it.
+type IgnoreRule struct {
+	Check   string
+	CaseNum string
+	Month   string
+}
+
+func (r IgnoreRule) matches(check string, finding Finding) bool {
+	if r.Check != "*" && r.Check != check {
+		return false
+	}
+	loc := finding.Location()
+	if r.CaseNum != "*" && (loc.Sheet == "" || r.CaseNum != loc.rowCell()) && (finding.Entry == nil || r.CaseNum != finding.Entry.CaseNum()) {
+		return false
+	}
+	if finding.Entry == nil {
+		return r.Month == "*"
+	}
+
+	return r.Month == "*" || r.Month == finding.Entry.Date().Format("2006-01")
+}
+
+var ignoref string
+var ignoreRules []IgnoreRule
+
+func loadIgnoreFile(path string) ([]IgnoreRule, error) {
+	var rules []IgnoreRule
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	var line int
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%s:%d: expected <check id> <case number> <month>", path, line)
+		}
+		rules = append(rules, IgnoreRule{Check: fields[0], CaseNum: fields[1], Month: fields[2]})
+	}
+
+	return rules, scanner.Err()
+}
+
+// filterIgnored drops findings covered by an ignore rule and returns how many were dropped
+func filterIgnored(results []CheckResult, rules []IgnoreRule) int {
+	var ignored int
+
+	for i, result := range results {
+		kept := result.Findings[:0]
+		for _, finding := range result.Findings {
+			if ignoreMatches(rules, result.ID, finding) {
+				ignored++
+				continue
+			}
+			kept = append(kept, finding)
+		}
+		results[i].Findings = kept
+	}
+
+	return ignored
+}
+
+func ignoreMatches(rules []IgnoreRule, check string, finding Finding) bool {
+	for _, rule := range rules {
+		if rule.matches(check, finding) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// invoiceLayout is a known invoice template, told apart by the headings of
+// its header row. Fields are the fields of the heading columns, "" for
+// columns the checks don't read such as the amount.
+type invoiceLayout struct {
+	name     string
+	headings []string
+	fields   []string
+}
+
+// invoiceLayouts are the invoice templates the agencies have used
+var invoiceLayouts = []invoiceLayout{
+	{"standard", []string{"No.", "案件番号", "種類", "納品日", "語数", "単価"}, []string{"no", "case", "type", "date", "words", "rate"}},
+	{"standard with amounts", []string{"No.", "案件番号", "種類", "納品日", "語数", "単価", "金額"}, []string{"no", "case", "type", "date", "words", "rate", ""}},
+	{"standard with PO", []string{"No.", "案件番号", "種類", "納品日", "語数", "単価", "PO番号"}, []string{"no", "case", "type", "date", "words", "rate", "po"}},
+	{"multi-client", []string{"No.", "クライアント", "案件番号", "種類", "納品日", "語数", "単価", "金額"}, []string{"no", "client", "case", "type", "date", "words", "rate", ""}},
+	{"english", []string{"No.", "Case", "Type", "Date", "Words", "Rate"}, []string{"no", "case", "type", "date", "words", "rate"}},
+	{"english with amounts", []string{"No.", "Case", "Type", "Date", "Words", "Rate", "Amount"}, []string{"no", "case", "type", "date", "words", "rate", ""}},
+}
+
+// invoiceColumns is the column mapping of the invoice being parsed,
+// --invoice-columns or the one of its detected layout
+var invoiceColumns = invoiceColumnsf
+
+// detectInvoiceLayout finds the header row of a known layout in rows. The
+// non-empty cells of the row have to be the layout's headings, in order and
+// nothing else, so a layout with more columns is never taken for a shorter one.
+func detectInvoiceLayout(rows [][]string) (invoiceLayout, *columnMapping, bool) {
+	for _, row := range rows {
+		var headings []string
+		var cols []int
+		for col, cell := range row {
+			if cell = strings.TrimSpace(cell); cell != "" {
+				headings = append(headings, cell)
+				cols = append(cols, col)
+			}
+		}
+
+		for _, layout := range invoiceLayouts {
+			if !sameHeadings(headings, layout.headings) {
+				continue
+			}
+			columns := &columnMapping{fields: invoiceColumnsf.fields, cols: make([]int, len(invoiceColumnsf.fields))}
+			for i := range columns.cols {
+				columns.cols[i] = -1
+			}
+			for i, field := range layout.fields {
+				if field != "" {
+					columns.cols[columns.field(field)] = cols[i]
+				}
+			}
+			return layout, columns, true
+		}
+	}
+
+	return invoiceLayout{}, nil, false
+}
+
+func sameHeadings(row, headings []string) bool {
+	if len(row) != len(headings) {
+		return false
+	}
+	for i := range row {
+		if !strings.EqualFold(row[i], headings[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// unknownLayoutError is returned for an invoice without entries and without
+// the header row of any known layout
+func unknownLayoutError(name, sheet string) error {
+	var names []string
+	for _, layout := range invoiceLayouts {
+		names = append(names, layout.name)
+	}
+
+	return fmt.Errorf("%s [%s]: no entries and no header row of a known invoice layout (%s), map the columns with --invoice-columns", name, sheet, strings.Join(names, ", "))
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SheetLayout is the column layout of the shuho sheets written before the
+// template changed, chosen by the sheet name or by the text of its header:
+//
+//	"shuho_layouts": [
+//	  {"sheets": "^(2020|2021)", "columns": "author=G"},
+//	  {"header": {"G": "担当者"}, "columns": "author=G"}
+//	]
+//
+// Header maps columns to the text a cell of that column has above the first
+// dated row. Columns are field=column pairs like --shuho-columns, on top of
+// it. The first layout that matches a sheet is used, sheets no layout matches
+// use --shuho-columns alone.
+type SheetLayout struct {
+	Sheets  string            `json:"sheets"`
+	Header  map[string]string `json:"header"`
+	Columns string            `json:"columns"`
+}
+
+func (l SheetLayout) matches(name string, rows [][]string) bool {
+	if l.Sheets == "" && len(l.Header) == 0 {
+		return false
+	}
+	if l.Sheets != "" {
+		if ok, _ := regexp.MatchString(l.Sheets, name); !ok {
+			return false
+		}
+	}
+
+	for column, text := range l.Header {
+		col, err := parseColumn(column)
+		if err != nil || !headerHasText(rows, col, text) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// headerHasText looks for text in the column of the rows above the first dated row
+func headerHasText(rows [][]string, col int, text string) bool {
+	date := shuhoColumnsf.cols[shuhoColumnsf.field("date")]
+	for _, row := range rows {
+		if date < len(row) && checkForValidDate(row[date]) {
+			break
+		}
+		if col < len(row) && strings.TrimSpace(row[col]) == text {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shuhoColumnsFor is the column mapping of a shuho sheet, --shuho-columns
+// with the columns of the first layout matching it
+func shuhoColumnsFor(name string, rows [][]string) *columnMapping {
+	for _, layout := range config.ShuhoLayouts {
+		if !layout.matches(name, rows) {
+			continue
+		}
+		columns := &columnMapping{fields: shuhoColumnsf.fields, cols: append([]int(nil), shuhoColumnsf.cols...)}
+		if err := columns.Set(layout.Columns); err != nil {
+			//loadConfig checked the columns, a layout built by hand may still be wrong
+			return shuhoColumnsf
+		}
+		return columns
+	}
+
+	return shuhoColumnsf
+}
+
+// checkLayouts is what loadConfig checks of the shuho_layouts
+func checkLayouts(layouts []SheetLayout) error {
+	for i, layout := range layouts {
+		if layout.Sheets == "" && len(layout.Header) == 0 {
+			return fmt.Errorf("shuho_layouts %d: needs sheets or header to match", i+1)
+		}
+		if _, err := regexp.Compile(layout.Sheets); err != nil {
+			return fmt.Errorf("shuho_layouts %d: %w", i+1, err)
+		}
+		for column := range layout.Header {
+			if _, err := parseColumn(column); err != nil {
+				return fmt.Errorf("shuho_layouts %d: %w", i+1, err)
+			}
+		}
+		columns := newColumnMapping(shuhoColumnsf.fields...)
+		if err := columns.Set(layout.Columns); err != nil {
+			return fmt.Errorf("shuho_layouts %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
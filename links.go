@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// hyperlinksf is auto, always or never: whether locations in the report are
+// OSC 8 hyperlinks to the cell, auto links them on a terminal
+var hyperlinksf = "auto"
+
+// hyperlinks is hyperlinksf resolved for this run
+var hyperlinks bool
+
+func useHyperlinks(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if outputf != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// sheet names Excel takes unquoted in a reference
+var plainSheetNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// Cell is the Excel reference of the cell, Sheet!G12, or of the first cell
+// of the row when there's no column, Sheet!A12
+func (l Location) Cell() string {
+	column := "A"
+	if l.Col > 0 {
+		column, _ = excelize.ColumnNumberToName(l.Col)
+	}
+
+	return cellRef(l.Sheet, column+strconv.Itoa(l.Row))
+}
+
+// rowCell is the first cell of the row, the reports and ignore rules name
+// the row by it
+func (l Location) rowCell() string {
+	return cellRef(l.Sheet, "A"+strconv.Itoa(l.Row))
+}
+
+// cellRef is Sheet!B12, the sheet name quoted when Excel needs it
+func cellRef(sheet, cell string) string {
+	if !plainSheetNameRe.MatchString(sheet) {
+		sheet = "'" + strings.ReplaceAll(sheet, "'", "''") + "'"
+	}
+
+	return sheet + "!" + cell
+}
+
+// URL is the xlsx://file#Sheet!A12 link to the cell, for editors and
+// terminals that open it in the workbook
+func (l Location) URL() string {
+	file, err := filepath.Abs(l.File)
+	if err != nil || isRemote(l.File) {
+		file = l.File
+	}
+	u := url.URL{Scheme: "xlsx", Path: filepath.ToSlash(file), Fragment: l.Cell()}
+
+	return u.String()
+}
+
+// link makes every mention of loc in text a hyperlink to its cell
+func link(text string, loc Location) string {
+	if !hyperlinks || loc.File == "" {
+		return text
+	}
+
+	return strings.ReplaceAll(text, loc.String(), "\033]8;;"+loc.URL()+"\033\\"+loc.String()+"\033]8;;\033\\")
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// newSheetCommand adds the sheet of a month to a shuho: a copy of its
+// template sheet named like the other month sheets, with the business days
+// of the month in the date column. Weekends, Japanese public holidays and the
+// days of --holidays are left out.
+func newSheetCommand(args []string) int {
+	fs := flag.NewFlagSet("new-sheet", flag.ExitOnError)
+	//from the first of the month, January 31 plus a month is March 3
+	today := time.Now()
+	nextMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	month := fs.String("month", nextMonth.Format("2006-01"), "month of the new sheet (YYYY-MM)")
+	output := fs.String("o", "", "write the shuho with the new sheet here instead of over it")
+	fs.BoolVar(&noBackupf, "no-backup", false, "overwrite the shuho without a timestamped copy of it")
+	checkFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(out, "\033[1;31mERROR Usage:\033[0m ./verifyshuho new-sheet [--month YYYY-MM] [--holidays days-off.txt] [-o out.xlsx] <shuho.xlsx>")
+		return 2
+	}
+	start, err := time.Parse("2006-01", *month)
+	if err != nil {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m Invalid month %s\n", *month)
+		return 2
+	}
+	if *output == "" {
+		*output = fs.Arg(0)
+	}
+	//the shuho layouts and the days off of the config
+	if err := loadSettings(); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	f, err := excelize.OpenFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	defer f.Close()
+
+	name, days, err := addMonthSheet(f, start)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 1
+	}
+	if _, err := saveWorkbook(f, *output); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	showCheckSuccess(printer.Sprintf("Added the sheet %s with %d business days to %s", name, days, *output))
+
+	return 0
+}
+
+// addMonthSheet copies the template sheet of f for the month starting at
+// start and returns the new sheet's name and the number of days filled in
+func addMonthSheet(f *excelize.File, start time.Time) (string, int, error) {
+	template, latest := "", ""
+	var latestMonth time.Time
+	for _, name := range f.GetSheetList() {
+		if templateSheetName(name) {
+			if template == "" {
+				template = name
+			}
+			continue
+		}
+		if month, ok := sheetMonth(name); ok && !month.Before(latestMonth) {
+			latest, latestMonth = name, month
+		}
+	}
+	if template == "" {
+		return "", 0, fmt.Errorf("no template sheet, name one with template, テンプレ or 雛形")
+	}
+
+	name := monthSheetName(latest, start)
+	if idx, _ := f.GetSheetIndex(name); idx >= 0 {
+		return "", 0, fmt.Errorf("the shuho already has a sheet %s", name)
+	}
+
+	from, _ := f.GetSheetIndex(template)
+	to, err := f.NewSheet(name)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := f.CopySheet(from, to); err != nil {
+		return "", 0, err
+	}
+
+	//the days go below the template's header, written like the dates of the latest month
+	rows, err := f.GetRows(name)
+	if err != nil {
+		return "", 0, err
+	}
+	row := len(rows) + 1
+	//the new sheet follows the layout of the latest month
+	var latestRows [][]string
+	if latest != "" {
+		if latestRows, err = f.GetRows(latest); err != nil {
+			return "", 0, err
+		}
+	}
+	columns := shuhoColumnsFor(latest, latestRows)
+	col := columns.cols[columns.field("date")] + 1
+	sample := latestDateCell(latestRows, columns)
+
+	var days int
+	for day := start; day.Month() == start.Month(); day = day.AddDate(0, 0, 1) {
+		if isDayOff(day) {
+			continue
+		}
+		cell, _ := excelize.CoordinatesToCellName(col, row)
+		if sample != "" && cellIsNumber(f, latest, sample) {
+			err = f.SetCellValue(name, cell, day)
+		} else {
+			err = f.SetCellStr(name, cell, fmt.Sprintf("%d/%d", day.Month(), day.Day()))
+		}
+		if sample != "" && err == nil {
+			var style int
+			if style, err = f.GetCellStyle(latest, sample); err == nil {
+				err = f.SetCellStyle(name, cell, cell, style)
+			}
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		row++
+		days++
+	}
+
+	return name, days, nil
+}
+
+// monthSheetName names the sheet of month like latest, 2024年7月 after
+// 2024年6月, or 2024-07 when there's no month sheet to follow
+func monthSheetName(latest string, month time.Time) string {
+	m := sheetMonthRe.FindStringSubmatchIndex(latest)
+	if _, weekly := sheetDay(latest); m == nil || weekly {
+		return month.Format("2006-01")
+	}
+
+	number := strconv.Itoa(int(month.Month()))
+	if m[5]-m[4] == 2 && len(number) == 1 {
+		number = "0" + number
+	}
+
+	return latest[:m[2]] + strconv.Itoa(month.Year()) + latest[m[3]:m[4]] + number + latest[m[5]:]
+}
+
+// latestDateCell is the date cell of the first dated row of the rows of the
+// latest month sheet
+func latestDateCell(rows [][]string, columns *columnMapping) string {
+	for i, row := range rows {
+		if row = columns.apply(row); checkForValidDate(row[0]) {
+			cell, _ := excelize.CoordinatesToCellName(columns.cols[columns.field("date")]+1, i+1)
+			return cell
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/width"
+)
+
+// normalizeCommand rewrites a shuho or invoice workbook into a cleaned copy
+// that parseShuho/parseInvoice accept without skipping rows
+func normalizeCommand(args []string) {
+	fs := flag.NewFlagSet("normalize", flag.ExitOnError)
+	kind := fs.String("kind", "", "workbook kind: shuho or invoice (detected when empty)")
+	fs.BoolVar(&noBackupf, "no-backup", false, "overwrite an existing out.xlsx without a timestamped copy of it")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		printer.Fprintf(out, "\033[1;31mERROR Usage:\033[0m ./verifyshuho normalize [--kind shuho|invoice] <in.xlsx> <out.xlsx>\n")
+		return
+	}
+
+	f, err := excelize.OpenFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		}
+	}()
+
+	if *kind == "" {
+		*kind = detectWorkbookKind(xlsxWorkbook{f})
+	}
+
+	var changed int
+	switch *kind {
+	case "shuho":
+		changed, err = normalizeShuho(f)
+	case "invoice":
+		changed, err = normalizeInvoice(f)
+	default:
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m Unknown workbook kind %q\n", *kind)
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return
+	}
+
+	if _, err := saveWorkbook(f, fs.Arg(1)); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return
+	}
+
+	showCheckSuccess(printer.Sprintf("Normalized %s workbook, %d cells changed -> %s", *kind, changed, fs.Arg(1)))
+}
+
+// invoices keep their dates in column D, shuhos in column A
+func detectWorkbookKind(f Workbook) string {
+	var invoiceDates, shuhoDates int
+
+	for _, name := range f.Sheets() {
+		rows, err := f.SheetRows(name)
+		if err != nil {
+			continue
+		}
+		for _, row := range rows {
+			if len(row) > 3 && normalizeInvoiceDate(row[3]) != "" {
+				invoiceDates++
+			}
+			if len(row) > 0 && normalizeShuhoDate(row[0]) != "" {
+				shuhoDates++
+			}
+		}
+	}
+
+	if invoiceDates > shuhoDates {
+		return "invoice"
+	}
+
+	return "shuho"
+}
+
+func normalizeShuho(f *excelize.File) (int, error) {
+	var changed int
+
+	for _, name := range f.GetSheetList() {
+		//leave the template sheet alone, parseShuho skips it too
+		if templateSheetName(name) {
+			continue
+		}
+
+		n, err := rewriteSheet(f, name, func(row []string, col int, value string) string {
+			value = normalizeText(value)
+			switch col {
+			case 0:
+				if date := normalizeShuhoDate(value); date != "" {
+					return date
+				}
+			case 1:
+				return normalizeCaseNumber(value)
+			case 3, 4:
+				return normalizeNumber(value)
+			}
+			return value
+		})
+		if err != nil {
+			return changed, err
+		}
+		changed += n
+	}
+
+	return changed, nil
+}
+
+func normalizeInvoice(f *excelize.File) (int, error) {
+	var sheetName string
+
+	//parseInvoice reads the last sheet
+	for _, name := range f.GetSheetList() {
+		sheetName = name
+	}
+
+	return rewriteSheet(f, sheetName, func(row []string, col int, value string) string {
+		value = normalizeText(value)
+		switch col {
+		case 1:
+			return normalizeCaseNumber(value)
+		case 3:
+			if date := normalizeInvoiceDate(value); date != "" {
+				return date
+			}
+		case 4, 5:
+			return normalizeNumber(value)
+		}
+		return value
+	})
+}
+
+// rewriteSheet applies fix to every cell of the sheet, writing back only the
+// changed text cells: numbers, dates and formulas keep their value and type
+func rewriteSheet(f *excelize.File, sheet string, fix func(row []string, col int, value string) string) (int, error) {
+	var changed int
+
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return 0, err
+	}
+
+	for r, row := range rows {
+		for c, value := range row {
+			cleaned := fix(row, c, value)
+			if cleaned == value {
+				continue
+			}
+
+			cell, err := excelize.CoordinatesToCellName(c+1, r+1)
+			if err != nil {
+				return changed, err
+			}
+			//a number formatted as 1,234 or a date cell shown as 6/5 is clean already
+			if formula, _ := f.GetCellFormula(sheet, cell); formula != "" || cellIsNumber(f, sheet, cell) {
+				continue
+			}
+			if err := f.SetCellStr(sheet, cell, cleaned); err != nil {
+				return changed, err
+			}
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+// full-width ASCII to half-width, half-width katakana to full-width, surrounding whitespace (including 　) removed
+func normalizeText(value string) string {
+	return strings.TrimSpace(width.Fold.String(value))
+}
+
+func normalizeCaseNumber(value string) string {
+	value = strings.ReplaceAll(value, ",", "")
+	value = strings.ReplaceAll(value, " ", "")
+
+	return strings.ToUpper(value)
+}
+
+// normalizeNumber reads a number written with either separator convention,
+// 1,234.5 or 1.234,5, full-width and spaced out included, and returns it as
+// 1234.5. A lone comma before three digits groups them, 1,234, any other
+// lone comma is a decimal one, 12,5. What isn't a number comes back folded
+// and without its spaces.
+func normalizeNumber(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\'' {
+			return -1
+		}
+		return r
+	}, width.Fold.String(value))
+
+	commas, dots := strings.Count(value, ","), strings.Count(value, ".")
+	grouping, decimal := ",", "."
+	switch {
+	case commas > 0 && dots > 0:
+		if strings.LastIndex(value, ",") > strings.LastIndex(value, ".") {
+			grouping, decimal = ".", ","
+		}
+	case commas == 1 && len(value)-strings.Index(value, ",")-1 != 3:
+		grouping, decimal = ".", ","
+	case dots > 1:
+		grouping, decimal = ".", ","
+	}
+
+	number := strings.Replace(strings.ReplaceAll(value, grouping, ""), decimal, ".", 1)
+	if _, err := strconv.ParseFloat(number, 64); err != nil {
+		return value
+	}
+
+	return number
+}
+
+var shuhoDateRe = regexp.MustCompile(`^(?:\d{4}[/.-])?(\d{1,2})[/.-月](\d{1,2})日?$`)
+
+// shuho dates are month/day without zero padding, e.g. 6/20
+func normalizeShuhoDate(value string) string {
+	m := shuhoDateRe.FindStringSubmatch(value)
+	if m == nil {
+		return ""
+	}
+
+	return strings.TrimLeft(m[1], "0") + "/" + strings.TrimLeft(m[2], "0")
+}
+
+var invoiceDateLayouts = []string{"01-02-06", "1-2-06", "01/02/06", "1/2/06", "01-02-2006", "1/2/2006", "2006-01-02", "2006/01/02", "2006/1/2"}
+
+// invoice dates are mm-dd-yy, the format getDate expects
+func normalizeInvoiceDate(value string) string {
+	for _, layout := range invoiceDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.Format("01-02-06")
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// odsWorkbook is an OpenDocument spreadsheet. Cells hold the text they
+// display, which for dates and numbers is the text office suites show.
+type odsWorkbook struct {
+	path   string
+	sheets []string
+	rows   map[string][][]string
+}
+
+func (w *odsWorkbook) Name() string {
+	return w.path
+}
+
+func (w *odsWorkbook) Sheets() []string {
+	return w.sheets
+}
+
+func (w *odsWorkbook) SheetRows(sheet string) ([][]string, error) {
+	rows, ok := w.rows[sheet]
+	if !ok {
+		return nil, fmt.Errorf("sheet %s does not exist", sheet)
+	}
+
+	return rows, nil
+}
+
+func (w *odsWorkbook) Close() error {
+	return nil
+}
+
+const (
+	odsTableNS  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	odsTextNS   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	odsOfficeNS = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+)
+
+func openODS(path string) (Workbook, error) {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	defer z.Close()
+
+	return readODS(path, &z.Reader)
+}
+
+// readODS reads the sheets out of content.xml
+func readODS(path string, z *zip.Reader) (Workbook, error) {
+	content, err := z.Open("content.xml")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	defer content.Close()
+
+	w := &odsWorkbook{path: path, rows: make(map[string][][]string)}
+	if err := w.read(content); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return w, nil
+}
+
+func odsAttr(e xml.StartElement, space, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value
+		}
+	}
+
+	return ""
+}
+
+// odsRepeat reads a number-*-repeated or -spanned attribute, 1 when absent
+func odsRepeat(e xml.StartElement, local string) int {
+	return odsCount(odsAttr(e, odsTableNS, local))
+}
+
+func odsCount(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 1
+	}
+
+	return n
+}
+
+// read walks content.xml. Runs of repeated rows and cells are only expanded
+// when they hold text, files routinely repeat an empty row a million times.
+func (w *odsWorkbook) read(r io.Reader) error {
+	d := xml.NewDecoder(r)
+
+	var sheet string
+	var rows [][]string
+	var merged []cellRange
+	var row, col, rowRepeat, colRepeat int
+	var cell *strings.Builder
+	var paragraphs int
+	var inParagraph bool
+	var fallback string
+
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Space == odsTableNS && t.Name.Local == "table":
+				sheet = odsAttr(t, odsTableNS, "name")
+				rows, merged, row = nil, nil, 0
+			case t.Name.Space == odsTableNS && t.Name.Local == "table-row":
+				rowRepeat, col = odsRepeat(t, "number-rows-repeated"), 0
+			case t.Name.Space == odsTableNS && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell"):
+				colRepeat = odsRepeat(t, "number-columns-repeated")
+				cell, paragraphs = &strings.Builder{}, 0
+				fallback = odsAttr(t, odsOfficeNS, "value")
+				if fallback == "" {
+					fallback = odsAttr(t, odsOfficeNS, "date-value")
+				}
+				rowSpan, colSpan := odsRepeat(t, "number-rows-spanned"), odsRepeat(t, "number-columns-spanned")
+				if rowSpan > 1 || colSpan > 1 {
+					merged = append(merged, cellRange{row, row + rowSpan - 1, col, col + colSpan - 1})
+				}
+			case t.Name.Space == odsOfficeNS && t.Name.Local == "annotation":
+				//comments aren't cell text
+				if err := d.Skip(); err != nil {
+					return err
+				}
+			case cell != nil && t.Name.Space == odsTextNS:
+				switch t.Name.Local {
+				case "p":
+					if paragraphs > 0 {
+						cell.WriteString("\n")
+					}
+					paragraphs++
+					inParagraph = true
+				case "s":
+					cell.WriteString(strings.Repeat(" ", odsCount(odsAttr(t, odsTextNS, "c"))))
+				case "tab":
+					cell.WriteString("\t")
+				case "line-break":
+					cell.WriteString("\n")
+				}
+			}
+		case xml.CharData:
+			if cell != nil && inParagraph {
+				cell.Write(t)
+			}
+		case xml.EndElement:
+			switch {
+			case t.Name.Space == odsTextNS && t.Name.Local == "p":
+				inParagraph = false
+			case t.Name.Space == odsTableNS && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell"):
+				text := cell.String()
+				if paragraphs == 0 {
+					text = fallback
+				}
+				if text != "" {
+					for i := 0; i < colRepeat; i++ {
+						rows = setCell(rows, row, col+i, text)
+					}
+				}
+				col += colRepeat
+				cell = nil
+			case t.Name.Space == odsTableNS && t.Name.Local == "table-row":
+				if rowRepeat > 1 && row < len(rows) {
+					for i := 1; i < rowRepeat; i++ {
+						rows = append(rows[:row+i], append([]string(nil), rows[row]...))
+					}
+				}
+				row += rowRepeat
+			case t.Name.Space == odsTableNS && t.Name.Local == "table":
+				w.sheets = append(w.sheets, sheet)
+				w.rows[sheet] = fillMergedRanges(rows, merged)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import "time"
+
+// overdueDaysf is how old a shuho entry may get before it has to be on an
+// invoice, 0 leaves the overdue check out
+var overdueDaysf int
+
+// overdueLookbackf is how far back the overdue check looks, older entries
+// were billed before the history started or are past billing anyway
+var overdueLookbackf = 90
+
+// ensureNothingOverdue reports shuho entries older than --overdue-days that
+// are on no invoice, neither this one nor any of the history. The entries
+// of the invoiced period are left to the shuho-in-invoice check.
+func ensureNothingOverdue(sentries, ientries []Entry, records []HistoryRecord) []Finding {
+	periodStart, _, ok := invoicePeriod(ientries)
+	if overdueDaysf <= 0 || !ok {
+		return nil
+	}
+	today := now()
+	cutoff := today.AddDate(0, 0, -overdueDaysf)
+	oldest := today.AddDate(0, 0, -overdueLookbackf)
+
+	invoiced := make(map[string]bool)
+	for _, ie := range ientries {
+		invoiced[ie.signature()] = true
+	}
+	for _, record := range records {
+		for _, e := range record.Entries {
+			invoiced[matchCase(e.Case)+" "+e.Type+" "+e.Words] = true
+		}
+	}
+
+	var findings []Finding
+	for _, se := range matchable(sentries) {
+		date := se.Date()
+		if date.Before(oldest) || date.After(cutoff) || !date.Before(periodStart) || invoiced[se.signature()] {
+			continue
+		}
+		days := int(today.Sub(date) / (24 * time.Hour))
+		findings = append(findings, Finding{Message: printer.Sprintf("Not invoiced after %d days: %s", days, se.String()), Entry: se, Severity: SeverityWarning})
+	}
+
+	return findings
+}
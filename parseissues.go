@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ParseError is a row that could not be read, parsing carries on past it
+type ParseError struct {
+	Location
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Location, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseIssue is a row with data that the parser skipped or could only partly use
+type ParseIssue struct {
+	Location
+	Reason string
+	Cells  []string
+}
+
+// every parse issue of this run, listed after the report
+var parseIssues []ParseIssue
+
+// the shuho entries of this run marked cancelled, kept out of the entries
+var cancelledEntries []Entry
+
+func addParseIssue(loc Location, reason string, row []string) {
+	parseIssues = append(parseIssues, ParseIssue{loc, reason, append([]string(nil), row...)})
+}
+
+func knownType(eType string) bool {
+	return eType == "翻訳" || eType == "英文チェック"
+}
+
+// checkParseIssues turns every parse issue into an error for --strict-parse
+func checkParseIssues(issues []ParseIssue) CheckResult {
+	result := CheckResult{ID: "parse", Name: "All rows parsed cleanly"}
+
+	for _, issue := range issues {
+		result.Findings = append(result.Findings, Finding{
+			Message: fmt.Sprintf("%s (%s): %s", issue.Location, translate(issue.Reason), strings.Join(issue.Cells, " | ")),
+			Loc:     issue.Location,
+		})
+	}
+
+	return result
+}
+
+// printParseIssues lists the rows that failed to parse (err) and the ones
+// that were skipped or questionable, both files together in sheet and row order
+func printParseIssues(err error, issues []ParseIssue) {
+	type line struct {
+		loc  Location
+		text string
+	}
+	var lines []line
+	var other []string
+
+	for _, e := range unjoinErrors(err) {
+		var pe *ParseError
+		if errors.As(e, &pe) {
+			lines = append(lines, line{pe.Location, fmt.Sprintf("\033[1;31mERROR:\033[0m %s", pe)})
+		} else {
+			other = append(other, fmt.Sprintf("\033[1;31mERROR:\033[0m %s", e))
+		}
+	}
+	for _, issue := range issues {
+		lines = append(lines, line{issue.Location, printer.Sprintf("\033[1;33mISSUE:\033[0m %s (%s): %s", issue.Location, translate(issue.Reason), strings.Join(issue.Cells, " | "))})
+	}
+
+	if len(lines) == 0 && len(other) == 0 {
+		return
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		a, b := lines[i].loc, lines[j].loc
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Sheet != b.Sheet {
+			return a.Sheet < b.Sheet
+		}
+		return a.Row < b.Row
+	})
+
+	fmt.Fprintln(out, "")
+	colorize(ColorYellow, translate("** Parse issues: "))
+	for _, text := range other {
+		fmt.Fprintln(out, text)
+	}
+	for _, l := range lines {
+		fmt.Fprintln(out, link(l.text, l.loc))
+	}
+}
+
+// unjoinErrors flattens errors.Join trees
+func unjoinErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, unjoinErrors(e)...)
+	}
+
+	return errs
+}
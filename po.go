@@ -0,0 +1,75 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+func init() {
+	RegisterCheck(poNumberCheck{})
+}
+
+// poNumberCheck holds every invoice row to a PO number, the one the shuho
+// gives its case. It only runs with the invoice po column mapped, and only
+// compares them with the shuho when any shuho row has a PO number, from
+// --shuho-columns, a shuho layout or a schema.
+type poNumberCheck struct{}
+
+func (poNumberCheck) Name() string {
+	return "po-numbers"
+}
+
+func (poNumberCheck) Description() string {
+	return "Invoice PO numbers match the shuho"
+}
+
+func (poNumberCheck) Run(shuho, invoice []Entry) []Finding {
+	if !invoiceColumns.mapped("po") {
+		return nil
+	}
+
+	//a case keeps its PO across months, the rows of earlier sheets count too
+	casePOs := make(map[string]map[string]bool)
+	for _, e := range shuho {
+		if se, ok := e.(ShuhoEntry); ok && se.SPO != "" {
+			key := matchCase(se.SCaseNum)
+			if casePOs[key] == nil {
+				casePOs[key] = make(map[string]bool)
+			}
+			casePOs[key][se.SPO] = true
+		}
+	}
+
+	var findings []Finding
+	for _, e := range invoice {
+		ie, ok := e.(InvoiceEntry)
+		if !ok {
+			continue
+		}
+		pos := casePOs[matchCase(ie.ICaseNum)]
+		switch {
+		case ie.po == "":
+			findings = append(findings, Finding{Message: printer.Sprintf("No PO number (Row %s)", ie.String()), Entry: ie})
+		//a shuho without PO numbers, none of its layouts maps them, isn't compared
+		case len(casePOs) == 0 || pos[ie.po]:
+		case len(pos) == 0:
+			findings = append(findings, Finding{Message: printer.Sprintf("No PO number for case %s in the shuho (Row %s)", ie.ICaseNum, ie.String()), Entry: ie, Severity: SeverityWarning})
+		default:
+			findings = append(findings, Finding{Message: printer.Sprintf("PO number %s isn't the shuho's %s (Row %s)", ie.po, strings.Join(sortedKeys(pos), ", "), ie.String()), Entry: ie})
+		}
+	}
+
+	return findings
+}
+
+// sortedKeys are the keys of m in order, for output that doesn't change
+// with the map's iteration order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
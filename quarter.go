@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// quarterTotals sum up the recorded months of a quarter
+type quarterTotals struct {
+	months       []string
+	translations float64
+	checks       float64
+	pretax       float64
+	// 翻訳, 英文チェック and the other types together, like typeShares
+	entries [3]int
+	words   [3]int
+	// the amount of the other types, which the pre-tax total leaves out
+	other float64
+}
+
+// quarterMonths are the YYYY-MM months of a quarter like 2024Q2, with
+// --fiscal-year the quarters count from April so 2024Q1 is April to June 2024
+func quarterMonths(quarter string) ([]string, error) {
+	year, q, ok := strings.Cut(strings.ToUpper(quarter), "Q")
+	y, err := strconv.Atoi(year)
+	n, qerr := strconv.Atoi(q)
+	if !ok || err != nil || qerr != nil || n < 1 || n > 4 {
+		return nil, fmt.Errorf("invalid quarter %q, use YYYYQn like 2024Q2", quarter)
+	}
+
+	first := time.Date(y, time.Month(3*(n-1)+1), 1, 0, 0, 0, 0, time.UTC)
+	if fiscalYearf {
+		first = first.AddDate(0, 3, 0)
+	}
+
+	return []string{first.Format("2006-01"), first.AddDate(0, 1, 0).Format("2006-01"), first.AddDate(0, 2, 0).Format("2006-01")}, nil
+}
+
+// previousQuarter is the quarter before a valid YYYYQn quarter
+func previousQuarter(quarter string) string {
+	year, q, _ := strings.Cut(strings.ToUpper(quarter), "Q")
+	y, _ := strconv.Atoi(year)
+	n, _ := strconv.Atoi(q)
+	if n == 1 {
+		return fmt.Sprintf("%dQ4", y-1)
+	}
+
+	return fmt.Sprintf("%dQ%d", y, n-1)
+}
+
+// sumQuarter sums the latest run of each month of the quarter in the history
+func sumQuarter(records []HistoryRecord, months []string) quarterTotals {
+	var totals quarterTotals
+	for _, record := range latestByMonth(records) {
+		if record.Month != months[0] && record.Month != months[1] && record.Month != months[2] {
+			continue
+		}
+		totals.months = append(totals.months, record.Month)
+		totals.translations += record.Translations
+		totals.checks += record.Checks
+		totals.pretax += record.Pretax
+		for _, e := range record.Entries {
+			i := 2
+			switch e.Type {
+			case "翻訳":
+				i = 0
+			case "英文チェック":
+				i = 1
+			}
+			words, _ := strconv.Atoi(normalizeNumber(e.Words))
+			if i == 2 {
+				date, _ := time.Parse("2006-01-02", e.Date)
+				totals.other += entryAmount(InvoiceEntry{IDate: date, ICaseNum: e.Case, IType: e.Type, IWordCount: normalizeNumber(e.Words), rate: e.Rate})
+			}
+			totals.entries[i]++
+			totals.words[i] += words
+		}
+	}
+
+	return totals
+}
+
+// quarterReportCommand prints the totals of a quarter from the history and
+// compares them with the quarter before
+func quarterReportCommand(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	quarter := fs.String("quarter", "", "quarter to report, YYYYQn like 2024Q2")
+	historyPath := fs.String("history", "", "history file written by --history (default history_file from the config)")
+	configPath := fs.String("config", "", "config file (default verifyshuho/config.json in the user config directory)")
+	fs.BoolVar(&fiscalYearf, "fiscal-year", false, "count the quarters from April, 2024Q1 is April to June 2024")
+	fs.Parse(args)
+
+	var err error
+	if config, err = loadConfig(*configPath); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	if *historyPath == "" {
+		*historyPath = config.HistoryFile
+	}
+	if *historyPath == "" || *quarter == "" {
+		fmt.Fprintln(out, "\033[1;31mERROR Usage:\033[0m ./verifyshuho report --quarter 2024Q2 [--fiscal-year] [--history history.jsonl]")
+		return 2
+	}
+	months, err := quarterMonths(*quarter)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	records, err := loadHistory(*historyPath)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	totals := sumQuarter(records, months)
+	if len(totals.months) == 0 {
+		printer.Fprintf(out, "No months of %s in %s\n", strings.ToUpper(*quarter), *historyPath)
+		return 1
+	}
+	printQuarter(strings.ToUpper(*quarter), months, totals)
+
+	previous := previousQuarter(*quarter)
+	earlierMonths, _ := quarterMonths(previous)
+	earlier := sumQuarter(records, earlierMonths)
+	switch {
+	case len(earlier.months) == 0:
+		printer.Fprintf(out, "No months of the previous quarter %s recorded\n", previous)
+	case earlier.pretax == 0:
+		printer.Fprintf(out, "Previous quarter %s: %s\n", previous, formatYen(earlier.pretax))
+	default:
+		printer.Fprintf(out, "Previous quarter %s: %s (%+.1f%%)\n", previous, formatYen(earlier.pretax), (totals.pretax-earlier.pretax)/earlier.pretax*100)
+	}
+
+	return 0
+}
+
+func printQuarter(quarter string, months []string, totals quarterTotals) {
+	printer.Fprintf(out, "%s (%s to %s), %d of 3 months recorded\n", quarter, months[0], months[2], len(totals.months))
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", translate("Type"), translate("Entries"), translate("Words"), translate("Amount"))
+	amounts := [3]float64{totals.translations, totals.checks, totals.other}
+	for i, label := range []string{typeLabel("翻訳"), typeLabel("英文チェック"), translate("other types")} {
+		if i == 2 && totals.entries[2] == 0 {
+			continue
+		}
+		printer.Fprintf(w, "%s\t%d\t%d\t%s\n", label, totals.entries[i], totals.words[i], formatYen(amounts[i]))
+	}
+	w.Flush()
+
+	printer.Fprintf(out, "Pre-tax total: %s\n", formatYen(totals.pretax))
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// defaultInvoiceRates are the rates without a rates config
+var defaultInvoiceRates = map[string]string{"翻訳": "18", "英文チェック": "1.4"}
+
+// invoiceRates is the rate of each type, what the rates check expects and
+// the rate table of the rate-table check, the rates config replaces it
+var invoiceRates = defaultInvoiceRates
+
+// RatePeriod is the rate of a type from one date until another, both
+// included and either left open, for a rate changing midway through a month:
+//
+//	"rate_periods": [{"type": "翻訳", "rate": "18", "until": "2023-06-14"}, {"type": "翻訳", "rate": "20", "from": "2023-06-15"}]
+type RatePeriod struct {
+	Type  string `json:"type"`
+	Rate  string `json:"rate"`
+	From  string `json:"from"`
+	Until string `json:"until"`
+}
+
+type ratePeriod struct {
+	RatePeriod
+	from, until time.Time
+}
+
+func (p ratePeriod) covers(date time.Time) bool {
+	return (p.from.IsZero() || !date.Before(p.from)) && (p.until.IsZero() || !date.After(p.until))
+}
+
+// the configured rate periods, see setRatePeriods
+var ratePeriods []ratePeriod
+
+// setRatePeriods reads the dates of the configured rate periods
+func setRatePeriods(periods []RatePeriod) error {
+	ratePeriods = nil
+	for i, p := range periods {
+		rp := ratePeriod{RatePeriod: p}
+		if _, err := strconv.ParseFloat(p.Rate, 64); err != nil {
+			return fmt.Errorf("rate_periods %d: rate %q isn't a number", i+1, p.Rate)
+		}
+		for _, date := range []struct {
+			value string
+			t     *time.Time
+		}{{p.From, &rp.from}, {p.Until, &rp.until}} {
+			if date.value == "" {
+				continue
+			}
+			t, err := time.Parse("2006-01-02", date.value)
+			if err != nil {
+				return fmt.Errorf("rate_periods %d: %w", i+1, err)
+			}
+			*date.t = t
+		}
+		ratePeriods = append(ratePeriods, rp)
+	}
+
+	return nil
+}
+
+// ratePeriodFor is the configured period of eType covering date
+func ratePeriodFor(eType string, date time.Time) (ratePeriod, bool) {
+	for _, p := range ratePeriods {
+		if p.Type == eType && p.covers(date) {
+			return p, true
+		}
+	}
+
+	return ratePeriod{}, false
+}
+
+// rateFor is the rate of eType on date, the one of its rate period or else
+// the one of invoiceRates
+func rateFor(eType string, date time.Time) (string, bool) {
+	if p, ok := ratePeriodFor(eType, date); ok {
+		return p.Rate, true
+	}
+	rate, ok := invoiceRates[eType]
+
+	return rate, ok
+}
+
+// RateTier bills the jobs of a type up to MaxWords words a flat amount
+// instead of per word, the rate column of their rows holds the amount:
+//
+//	"rate_tiers": [{"type": "翻訳", "max_words": 300, "flat": "5000"}]
+type RateTier struct {
+	Type     string `json:"type"`
+	MaxWords int    `json:"max_words"`
+	Flat     string `json:"flat"`
+}
+
+// the configured rate tiers, smallest first, see setRateTiers
+var rateTiers []RateTier
+
+func setRateTiers(tiers []RateTier) error {
+	for i, tier := range tiers {
+		if _, err := strconv.ParseFloat(tier.Flat, 64); err != nil {
+			return fmt.Errorf("rate_tiers %d: flat %q isn't a number", i+1, tier.Flat)
+		}
+		if tier.MaxWords <= 0 {
+			return fmt.Errorf("rate_tiers %d: max_words must be above 0", i+1)
+		}
+	}
+	rateTiers = append([]RateTier(nil), tiers...)
+	sort.SliceStable(rateTiers, func(i, j int) bool { return rateTiers[i].MaxWords < rateTiers[j].MaxWords })
+
+	return nil
+}
+
+// tierFor is the smallest tier of the entry's type its words fit in
+func tierFor(e Entry) (RateTier, bool) {
+	words, err := strconv.Atoi(e.WordCount())
+	if err != nil {
+		return RateTier{}, false
+	}
+	for _, tier := range rateTiers {
+		if tier.Type == e.Type() && words <= tier.MaxWords {
+			return tier, true
+		}
+	}
+
+	return RateTier{}, false
+}
+
+// expectedRate is what the rate column of an invoice entry should hold,
+// the flat amount of its tier or the rate of its type on its date
+func expectedRate(e Entry) (string, bool) {
+	if tier, ok := tierFor(e); ok {
+		return tier.Flat, true
+	}
+	if _, rate, ok := clientRate(e); ok {
+		return rate, true
+	}
+
+	return rateFor(e.Type(), e.Date())
+}
+
+// entryAmount is what an invoice entry bills, its flat amount or its words
+// times its rate
+func entryAmount(e Entry) float64 {
+	if tier, ok := tierFor(e); ok {
+		flat, _ := strconv.ParseFloat(tier.Flat, 64)
+		return flat
+	}
+	rate, _ := strconv.ParseFloat(e.Rate(), 64)
+	words, err := strconv.ParseFloat(e.WordCount(), 64)
+	if err != nil {
+		fmt.Fprintln(out, err)
+	}
+
+	return words * rate
+}
+
+// ClientProfile is what differs for the rows of a client on an invoice
+// billing several, read from the client column:
+//
+//	"clients": {"Acme": {"rates": {"翻訳": "20", "英文チェック": "1.6"}}}
+type ClientProfile struct {
+	// type -> rate, the types left out have the usual rate
+	Rates map[string]string `json:"rates"`
+}
+
+// clientRate is the rate of an invoice entry's type in its client's profile
+func clientRate(e Entry) (string, string, bool) {
+	ie, ok := e.(InvoiceEntry)
+	if !ok || ie.client == "" {
+		return "", "", false
+	}
+	rate, ok := config.Clients[ie.client].Rates[ie.IType]
+
+	return ie.client, rate, ok
+}
+
+// rateTable is every rate an invoice row may have
+func rateTable() []string {
+	set := make(map[string]bool)
+	for _, rate := range invoiceRates {
+		set[rate] = true
+	}
+	for _, p := range ratePeriods {
+		set[p.Rate] = true
+	}
+	for _, profile := range config.Clients {
+		for _, rate := range profile.Rates {
+			set[rate] = true
+		}
+	}
+	rates := make([]string, 0, len(set))
+	for rate := range set {
+		rates = append(rates, rate)
+	}
+	sort.Strings(rates)
+
+	return rates
+}
+
+// sameRate compares rates as numbers, 1.40 is 1.4
+func sameRate(a, b string) bool {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+
+	return x == y
+}
+
+// Rounding is how the agency rounds amounts to the yen, the amounts keep
+// their fractions when no mode is set:
+//
+//	"rounding": {"mode": "floor", "per_line": true}
+//
+// mode is half-up, half-even or floor, per_line rounds the amount of every
+// line before adding them up instead of the totals.
+type Rounding struct {
+	Mode    string `json:"mode"`
+	PerLine bool   `json:"per_line"`
+}
+
+var roundingModes = []string{"half-up", "half-even", "floor"}
+
+// roundYen rounds amount to the yen by mode, the float error of rate times
+// words, 6.999999 for 1.4 × 5 say, is taken off first
+func roundYen(amount float64, mode string) float64 {
+	amount = math.Round(amount*1e6) / 1e6
+	switch mode {
+	case "half-up":
+		return math.Round(amount)
+	case "half-even":
+		return math.RoundToEven(amount)
+	case "floor":
+		return math.Floor(amount)
+	}
+
+	return amount
+}
+
+// lineAmount is what an invoice entry adds to the total, rounded when the
+// agency rounds every line
+func lineAmount(e Entry) float64 {
+	if config.Rounding.PerLine {
+		return roundYen(entryAmount(e), config.Rounding.Mode)
+	}
+
+	return entryAmount(e)
+}
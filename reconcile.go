@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// deposit is an incoming payment of a bank statement
+type deposit struct {
+	date        time.Time
+	amount      float64
+	description string
+	used        bool
+}
+
+// bankDateColumns, bankAmountColumns and bankDescriptionColumns are the
+// headings banks give the columns of their CSV statements. A single amount
+// column has the withdrawals as negative amounts.
+var (
+	bankDateColumns        = []string{"日付", "取引日", "お取引日", "年月日", "date", "transaction date", "posting date"}
+	bankDepositColumns     = []string{"入金", "入金額", "入金金額", "お預入れ", "お預入れ金額", "預入金額", "deposit", "deposits", "credit", "credit amount"}
+	bankAmountColumns      = []string{"金額", "取引金額", "amount"}
+	bankDescriptionColumns = []string{"摘要", "内容", "お取引内容", "取引内容", "description", "details", "memo"}
+	bankDateLayouts        = []string{"2006/01/02", "2006-01-02", "2006/1/2", "2006-1-2", "20060102", "2006年1月2日", "01/02/2006"}
+)
+
+// loadBankStatement reads the deposits of a bank statement CSV, Shift_JIS
+// like most Japanese banks export it or UTF-8
+func loadBankStatement(path string) ([]*deposit, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) {
+		if data, err = japanese.ShiftJIS.NewDecoder().Bytes(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	//some banks put the account details above the heading row
+	header := -1
+	var dateCol, depositCol, amountCol, descriptionCol int
+	for i, row := range rows {
+		dateCol, depositCol = bankColumn(row, bankDateColumns), bankColumn(row, bankDepositColumns)
+		amountCol, descriptionCol = bankColumn(row, bankAmountColumns), bankColumn(row, bankDescriptionColumns)
+		if dateCol >= 0 && (depositCol >= 0 || amountCol >= 0) {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, fmt.Errorf("%s: no date and deposit or amount columns, not a bank statement", path)
+	}
+	if depositCol < 0 {
+		depositCol = amountCol
+	}
+
+	var deposits []*deposit
+	for _, row := range rows[header+1:] {
+		if len(row) <= dateCol || len(row) <= depositCol {
+			continue
+		}
+		date, ok := parseBankDate(row[dateCol])
+		amount, err := strconv.ParseFloat(strings.NewReplacer(",", "", "¥", "", "円", "", " ", "").Replace(normalizeNumber(row[depositCol])), 64)
+		//balance carried forward, withdrawals and totals
+		if !ok || err != nil || amount <= 0 {
+			continue
+		}
+		d := &deposit{date: date, amount: amount}
+		if descriptionCol >= 0 && descriptionCol < len(row) {
+			d.description = strings.TrimSpace(row[descriptionCol])
+		}
+		deposits = append(deposits, d)
+	}
+
+	return deposits, nil
+}
+
+func bankColumn(row []string, names []string) int {
+	for i, cell := range row {
+		cell = strings.ToLower(strings.TrimSpace(cell))
+		for _, name := range names {
+			if cell == name {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func parseBankDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range bankDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// payment is what reconcile found for an invoiced month
+type payment struct {
+	month    string
+	expected float64
+	paid     float64
+	date     time.Time
+	// paid, short, unpaid or pending when the statement ends before the payment is due
+	status string
+}
+
+// reconcilePayments matches the months of the history to deposits of their
+// net amount, the pre-tax total minus the withholding, made after the month
+// and within the days. A deposit up to fee short of it still pays the month,
+// the transfer fee is often taken off. With payer, the payer's deposits in
+// the window are the month's payment, whatever their amount, so short
+// payments are told apart from unpaid months.
+func reconcilePayments(records []HistoryRecord, deposits []*deposit, within int, fee float64, payer string) []payment {
+	var statementEnd time.Time
+	for _, d := range deposits {
+		if d.date.After(statementEnd) {
+			statementEnd = d.date
+		}
+	}
+
+	var payments []payment
+	for _, record := range latestByMonth(records) {
+		month, err := time.Parse("2006-01", record.Month)
+		if err != nil || record.Pretax <= 0 {
+			continue
+		}
+		pretax := math.Round(record.Pretax)
+		p := payment{month: record.Month, expected: pretax - withholdingTax(pretax), status: "unpaid"}
+		start := month.AddDate(0, 1, 0)
+		end := start.AddDate(0, 0, within)
+
+		var candidates []*deposit
+		for _, d := range deposits {
+			if d.used || d.date.Before(start) || d.date.After(end) {
+				continue
+			}
+			if payer != "" && !strings.Contains(strings.ToLower(d.description), strings.ToLower(payer)) {
+				continue
+			}
+			candidates = append(candidates, d)
+		}
+		for _, d := range candidates {
+			if d.amount <= p.expected && d.amount >= p.expected-fee {
+				d.used = true
+				p.paid, p.date, p.status = d.amount, d.date, "paid"
+				break
+			}
+		}
+		if p.status != "paid" && payer != "" && len(candidates) > 0 {
+			for _, d := range candidates {
+				d.used = true
+				p.paid += d.amount
+				p.date = d.date
+			}
+			p.status = "short"
+			if p.paid >= p.expected-fee {
+				p.status = "paid"
+			}
+		}
+		if p.status == "unpaid" && end.After(statementEnd) {
+			p.status = "pending"
+		}
+		payments = append(payments, p)
+	}
+
+	return payments
+}
+
+// reconcileCommand prints whether every month of the history was paid
+// according to a bank statement
+func reconcileCommand(args []string) int {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	within := fs.Int("within", 75, "days after the end of a month its payment may arrive in")
+	fee := fs.Float64("fee", 880, "how many yen short a deposit may be for the transfer fee")
+	payer := fs.String("payer", "", "text of the agency's deposits in the statement description, tells short payments from unpaid months")
+	historyPath := fs.String("history", "", "history file written by --history (default history_file from the config)")
+	configPath := fs.String("config", "", "config file (default verifyshuho/config.json in the user config directory)")
+	fs.Parse(args)
+
+	var err error
+	if config, err = loadConfig(*configPath); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	if *historyPath == "" {
+		*historyPath = config.HistoryFile
+	}
+	if *historyPath == "" || fs.NArg() != 1 {
+		fmt.Fprintln(out, "\033[1;31mERROR Usage:\033[0m ./verifyshuho reconcile [--payer name] [--within days] [--fee yen] --history history.jsonl <statement.csv>")
+		return 2
+	}
+	records, err := loadHistory(*historyPath)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	deposits, err := loadBankStatement(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	status := 0
+	for _, p := range reconcilePayments(records, deposits, *within, *fee, *payer) {
+		printPayment(out, p)
+		if p.status == "short" || p.status == "unpaid" {
+			status = 1
+		}
+	}
+
+	return status
+}
+
+func printPayment(w io.Writer, p payment) {
+	switch p.status {
+	case "paid":
+		printer.Fprintf(w, "\033[1;32mPAID:\033[0m %s %s on %s (expected %s)\n", p.month, formatYen(p.paid), p.date.Format("2006-01-02"), formatYen(p.expected))
+	case "short":
+		printer.Fprintf(w, "\033[1;31mSHORT:\033[0m %s %s paid by %s, %s short of %s\n", p.month, formatYen(p.paid), p.date.Format("2006-01-02"), formatYen(p.expected-p.paid), formatYen(p.expected))
+	case "unpaid":
+		printer.Fprintf(w, "\033[1;31mUNPAID:\033[0m %s expected %s\n", p.month, formatYen(p.expected))
+	case "pending":
+		printer.Fprintf(w, "\033[1;33mPENDING:\033[0m %s expected %s after the end of the statement\n", p.month, formatYen(p.expected))
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// isRemote is true for workbook arguments downloaded instead of opened.
+// Plain http:// isn't one of them, invoices aren't sent unencrypted.
+func isRemote(p string) bool {
+	return strings.HasPrefix(p, "https://") || strings.HasPrefix(p, "s3://")
+}
+
+// remoteClient downloads the remote workbooks, a stalled server fails the
+// run instead of hanging it
+var remoteClient = &http.Client{Timeout: 2 * time.Minute}
+
+// openRemote downloads the workbook at an https:// or s3:// URL. s3:// URLs
+// are signed with the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN from the environment, in AWS_REGION, at AWS_ENDPOINT_URL
+// for S3 compatible storage. Without a key the object is fetched anonymously.
+// SharePoint and OneDrive share links go through Graph, see shareLinkRequest.
+func openRemote(rawURL string) (Workbook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := remoteRequest(u)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rawURL, err)
+	}
+	resp, err := remoteClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", rawURL, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rawURL, err)
+	}
+	readChecksums[rawURL] = fmt.Sprintf("%x", sha256.Sum256(data))
+
+	switch strings.ToLower(path.Ext(u.Path)) {
+	case ".json", ".csv":
+		return readExport(u.Path, bytes.NewReader(data))
+	}
+
+	return workbookFromData(rawURL, data)
+}
+
+func remoteRequest(u *url.URL) (*http.Request, error) {
+	if isShareLink(u) {
+		return shareLinkRequest(u.String())
+	}
+	if u.Scheme != "s3" {
+		return http.NewRequest(http.MethodGet, u.String(), nil)
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	//path style on custom endpoints, virtual hosted on AWS
+	key := strings.TrimPrefix(u.Path, "/")
+	object := "https://" + u.Host + ".s3." + region + ".amazonaws.com/" + awsURIEncode(key)
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+		object = strings.TrimSuffix(endpoint, "/") + "/" + u.Host + "/" + awsURIEncode(key)
+	}
+	req, err := http.NewRequest(http.MethodGet, object, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if accessKey := os.Getenv("AWS_ACCESS_KEY_ID"); accessKey != "" {
+		signAWSv4(req, accessKey, os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN"), region, time.Now().UTC())
+	}
+
+	return req, nil
+}
+
+// emptyPayloadHash is the SHA-256 of the empty body of a GET
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// signAWSv4 adds the Signature Version 4 authorization of a bodiless S3
+// request, signing the host and every header already set on req
+func signAWSv4(req *http.Request, accessKey, secretKey, sessionToken, region string, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	fmt.Fprintf(&canonical, "%s\n%s\n%s\n", req.Method, req.URL.EscapedPath(), req.URL.RawQuery)
+	for _, name := range names {
+		fmt.Fprintf(&canonical, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+	fmt.Fprintf(&canonical, "\n%s\n%s", signedHeaders, emptyPayloadHash)
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonical.String()))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+
+	return h.Sum(nil)
+}
+
+// awsURIEncode escapes an object key the way S3 signs it, everything but
+// letters, digits, -._~ and the / between segments
+func awsURIEncode(key string) string {
+	var b strings.Builder
+	for _, c := range []byte(key) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', strings.IndexByte("-._~/", c) >= 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// reportSpecs collects --report format[:path] flags, it can be given more than once
+type reportSpecs []string
+
+func (r *reportSpecs) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *reportSpecs) Set(value string) error {
+	format, _, _ := strings.Cut(value, ":")
+	if _, ok := reportWriters[format]; !ok {
+		return fmt.Errorf("unknown report format %q", format)
+	}
+	*r = append(*r, value)
+
+	return nil
+}
+
+var reportsf reportSpecs
+
+// report writers render check results in a machine readable format
+var reportWriters = map[string]func(path string, results []CheckResult) error{
+	"junit":  writeJUnitReport,
+	"tap":    writeTAPReport,
+	"github": writeGitHubReport,
+	"html":   writeHTMLReport,
+}
+
+func writeReports(results []CheckResult) {
+	for _, spec := range reportsf {
+		format, path, _ := strings.Cut(spec, ":")
+		if err := reportWriters[format](path, results); err != nil {
+			printer.Fprintf(out, "\033[1;31mERROR:\033[0m %s report: %s\n", format, err)
+		}
+	}
+}
+
+// createReportFile opens path for writing, an empty path is stdout
+func createReportFile(path string) (*os.File, error) {
+	if path == "" || path == "-" {
+		return os.Stdout, nil
+	}
+
+	return os.Create(path)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+// the input checksums are suite properties named sha256:<path>
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// one test case per check without findings and one per finding, so every
+// mismatch shows up as its own line in the CI test tab. Errors and warnings
+// are failures typed by severity, info findings pass with the message as output.
+func writeJUnitReport(path string, results []CheckResult) error {
+	suite := junitTestSuite{Name: "verifyshuho"}
+	for _, input := range inputFiles {
+		suite.Properties = append(suite.Properties, junitProperty{"sha256:" + input.Path, input.SHA256})
+	}
+
+	for _, result := range results {
+		if len(result.Findings) == 0 {
+			suite.Cases = append(suite.Cases, junitTestCase{ClassName: "verifyshuho." + result.ID, Name: result.Name})
+			continue
+		}
+
+		for _, finding := range result.Findings {
+			tc := junitTestCase{ClassName: "verifyshuho." + result.ID, Name: finding.Message}
+			if finding.Severity == SeverityInfo {
+				tc.SystemOut = "info: " + finding.Message
+			} else {
+				tc.Failure = &junitFailure{Message: finding.Message, Type: finding.Severity.String(), Text: result.Name}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+	}
+	suite.Tests = len(suite.Cases)
+
+	f, err := createReportFile(path)
+	if err != nil {
+		return err
+	}
+	if f != os.Stdout {
+		defer f.Close()
+	}
+
+	doc := junitTestSuites{Tests: suite.Tests, Failures: suite.Failures, Suites: []junitTestSuite{suite}}
+	fmt.Fprint(f, xml.Header)
+	enc := xml.NewEncoder(f)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(f)
+	return err
+}
+
+// each check is one TAP test line, findings follow as diagnostics prefixed with their severity
+func writeTAPReport(path string, results []CheckResult) error {
+	f, err := createReportFile(path)
+	if err != nil {
+		return err
+	}
+	if f != os.Stdout {
+		defer f.Close()
+	}
+
+	fmt.Fprintln(f, "TAP version 13")
+	fmt.Fprintf(f, "1..%d\n", len(results))
+	for _, input := range inputFiles {
+		fmt.Fprintf(f, "# sha256 %s: %s\n", input.Path, input.SHA256)
+	}
+	for i, result := range results {
+		status := "ok"
+		if !result.Passed() {
+			status = "not ok"
+		}
+		fmt.Fprintf(f, "%s %d - %s: %s\n", status, i+1, result.ID, result.Name)
+
+		for _, finding := range result.Findings {
+			fmt.Fprintf(f, "# %s: %s\n", finding.Severity, finding.Message)
+		}
+	}
+
+	return nil
+}
+
+// GitHub Actions workflow commands, a finding is annotated on its workbook
+// with the spreadsheet row standing in for the line number
+func writeGitHubReport(path string, results []CheckResult) error {
+	f, err := createReportFile(path)
+	if err != nil {
+		return err
+	}
+	if f != os.Stdout {
+		defer f.Close()
+	}
+
+	for _, input := range inputFiles {
+		fmt.Fprintf(f, "::notice title=SHA-256::%s\n", githubEscapeData(input.Path+" "+input.SHA256))
+	}
+	for _, result := range results {
+		for _, finding := range result.Findings {
+			loc := finding.Location()
+			fmt.Fprintf(f, "::%s file=%s,line=%d,title=%s::%s\n", githubCommands[finding.Severity],
+				githubEscapeProperty(loc.File), loc.Row, githubEscapeProperty(result.ID+" ("+loc.Sheet+")"), githubEscapeData(finding.Message))
+		}
+	}
+
+	return nil
+}
+
+var githubCommands = map[Severity]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityInfo:    "notice",
+}
+
+func githubEscapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func githubEscapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>verifyshuho report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+h2 { font-size: 1.1em; margin-top: 1.5em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; }
+.passed { color: #1a7f37; }
+.failed, .error { color: #cf222e; }
+.warning { color: #9a6700; }
+.info { color: #0969da; }
+</style>
+</head>
+<body>
+<h1>verifyshuho report</h1>
+<p>{{.Generated}}: {{.Passed}} of {{len .Results}} checks passed</p>
+{{range .Inputs}}<p><small>SHA-256 {{.Path}}: <code>{{.SHA256}}</code></small></p>
+{{end}}{{range .Results}}
+<h2 class="{{if .Passed}}passed{{else}}failed{{end}}">{{if .Passed}}&#10003;{{else}}&#10007;{{end}} {{.Name}} <small>({{.ID}})</small></h2>
+{{if .Findings}}
+<table>
+<tr><th>Severity</th><th>Location</th><th>Finding</th></tr>
+{{range .Findings}}<tr class="{{.Severity}}"><td>{{.Severity}}</td><td>{{.Location}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// a standalone page with one section per check, --open shows it in the
+// browser, in a temporary file when no path was given
+func writeHTMLReport(path string, results []CheckResult) error {
+	if openf && (path == "" || path == "-") {
+		path = filepath.Join(os.TempDir(), "verifyshuho-report.html")
+	}
+
+	f, err := createReportFile(path)
+	if err != nil {
+		return err
+	}
+	if f != os.Stdout {
+		defer f.Close()
+	}
+
+	var passed int
+	for _, result := range results {
+		if result.Passed() {
+			passed++
+		}
+	}
+
+	data := struct {
+		Generated string
+		Passed    int
+		Results   []CheckResult
+		Inputs    []inputFile
+	}{now().Format("2006-01-02 15:04"), passed, results, inputFiles}
+	if err := htmlReportTemplate.Execute(f, data); err != nil {
+		return err
+	}
+
+	if openf {
+		return openInBrowser(path)
+	}
+
+	return nil
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rule is a declarative check from the "rules" list of the config, e.g.
+//
+//	{"name": "max-words", "expr": "wordcount < 20000", "severity": "warning"}
+//	{"name": "translation-rate", "expr": "rate in [18, 20] when type == 翻訳", "entries": "invoice"}
+//
+// An entry breaking expr becomes a finding. Expressions compare the fields
+// wordcount, rate, type, case, date (2006-01-02), month (2006-01) and author
+// with ==, !=, <, <=, >, >=, in [..] and not in [..], combined with and, or,
+// not and parentheses. Values that both parse as numbers compare numerically,
+// bare words that aren't field names are strings.
+type Rule struct {
+	Name     string `json:"name"`
+	Expr     string `json:"expr"`
+	When     string `json:"when"`
+	Severity string `json:"severity"`
+	// invoice, shuho or both (the default)
+	Entries string `json:"entries"`
+	Message string `json:"message"`
+}
+
+// ruleCheck runs a Rule as a registered Check
+type ruleCheck struct {
+	rule     Rule
+	expr     ruleNode
+	when     ruleNode
+	severity Severity
+}
+
+func newRuleCheck(rule Rule) (*ruleCheck, error) {
+	c := &ruleCheck{rule: rule}
+
+	if rule.Name == "" {
+		return nil, fmt.Errorf("rule %q has no name", rule.Expr)
+	}
+
+	text, when, found := strings.Cut(rule.Expr, " when ")
+	if found && rule.When != "" {
+		return nil, fmt.Errorf("rule %s: both an inline when and a when field", rule.Name)
+	}
+	if !found {
+		when = rule.When
+	}
+
+	var err error
+	if c.expr, err = parseRuleExpr(text); err != nil {
+		return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+	}
+	if strings.TrimSpace(when) != "" {
+		if c.when, err = parseRuleExpr(when); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+	}
+
+	c.severity = SeverityError
+	if rule.Severity != "" {
+		severity, ok := parseSeverity(rule.Severity)
+		if !ok {
+			return nil, fmt.Errorf("rule %s: unknown severity %q", rule.Name, rule.Severity)
+		}
+		c.severity = severity
+	}
+
+	switch rule.Entries {
+	case "", "both", "invoice", "shuho":
+	default:
+		return nil, fmt.Errorf("rule %s: entries must be invoice, shuho or both", rule.Name)
+	}
+
+	return c, nil
+}
+
+func (c *ruleCheck) Name() string {
+	return "rule:" + c.rule.Name
+}
+
+func (c *ruleCheck) Description() string {
+	return printer.Sprintf("Rule %s holds (%s)", c.rule.Name, c.rule.Expr)
+}
+
+func (c *ruleCheck) Run(shuho, invoice []Entry) []Finding {
+	var entries []Entry
+	var findings []Finding
+
+	if c.rule.Entries != "shuho" {
+		entries = append(entries, invoice...)
+	}
+	if c.rule.Entries != "invoice" && len(invoice) > 0 {
+		entries = append(entries, getScopedShuho(shuho, invoice)...)
+	}
+
+	for _, entry := range entries {
+		fields := ruleFields(entry)
+		if c.when != nil && !c.when.eval(fields).truthy() {
+			continue
+		}
+		if c.expr.eval(fields).truthy() {
+			continue
+		}
+
+		message := c.rule.Message
+		if message == "" {
+			message = printer.Sprintf("Rule %s broken (%s)", c.rule.Name, c.rule.Expr)
+		}
+		findings = append(findings, Finding{Message: fmt.Sprintf("%s: %s", message, entry.String()), Entry: entry, Severity: c.severity})
+	}
+
+	return findings
+}
+
+// the checks of the configured rules, run after the registered checks
+var ruleChecks []Check
+
+// registerRules turns the configured rules into checks, replacing the ones of
+// an earlier config so a second verification doesn't run them twice
+func registerRules(rules []Rule) error {
+	var registered []Check
+	for _, rule := range rules {
+		c, err := newRuleCheck(rule)
+		if err != nil {
+			return err
+		}
+		registered = append(registered, c)
+	}
+	ruleChecks = registered
+
+	return nil
+}
+
+func ruleFields(e Entry) map[string]string {
+	fields := map[string]string{
+		"wordcount": e.WordCount(),
+		"rate":      e.Rate(),
+		"type":      e.Type(),
+		"case":      e.CaseNum(),
+		"date":      e.Date().Format("2006-01-02"),
+		"month":     e.Date().Format("2006-01"),
+		"author":    "",
+	}
+	if se, ok := e.(ShuhoEntry); ok {
+		fields["author"] = se.SAuthor
+	}
+
+	return fields
+}
+
+// ruleValue is either a single value or the list of an in [..] expression
+type ruleValue struct {
+	text string
+	list []string
+}
+
+func (v ruleValue) truthy() bool {
+	return v.text == "true"
+}
+
+func ruleBool(b bool) ruleValue {
+	if b {
+		return ruleValue{text: "true"}
+	}
+
+	return ruleValue{text: "false"}
+}
+
+type ruleNode interface {
+	eval(fields map[string]string) ruleValue
+}
+
+type ruleLiteral string
+
+func (n ruleLiteral) eval(fields map[string]string) ruleValue {
+	return ruleValue{text: string(n)}
+}
+
+// ruleWord is a field name, or a bare string when no such field exists
+type ruleWord string
+
+func (n ruleWord) eval(fields map[string]string) ruleValue {
+	if value, ok := fields[strings.ToLower(string(n))]; ok {
+		return ruleValue{text: value}
+	}
+
+	return ruleValue{text: string(n)}
+}
+
+type ruleList []ruleNode
+
+func (n ruleList) eval(fields map[string]string) ruleValue {
+	var v ruleValue
+	for _, item := range n {
+		v.list = append(v.list, item.eval(fields).text)
+	}
+
+	return v
+}
+
+type ruleNot struct {
+	x ruleNode
+}
+
+func (n ruleNot) eval(fields map[string]string) ruleValue {
+	return ruleBool(!n.x.eval(fields).truthy())
+}
+
+type ruleBinary struct {
+	op   string
+	l, r ruleNode
+}
+
+func (n ruleBinary) eval(fields map[string]string) ruleValue {
+	switch n.op {
+	case "and":
+		return ruleBool(n.l.eval(fields).truthy() && n.r.eval(fields).truthy())
+	case "or":
+		return ruleBool(n.l.eval(fields).truthy() || n.r.eval(fields).truthy())
+	}
+
+	l, r := n.l.eval(fields), n.r.eval(fields)
+	switch n.op {
+	case "in", "not in":
+		var found bool
+		for _, item := range r.list {
+			if compareRuleValues(l.text, item) == 0 {
+				found = true
+			}
+		}
+		return ruleBool(found == (n.op == "in"))
+	}
+
+	c := compareRuleValues(l.text, r.text)
+	switch n.op {
+	case "==":
+		return ruleBool(c == 0)
+	case "!=":
+		return ruleBool(c != 0)
+	case "<":
+		return ruleBool(c < 0)
+	case "<=":
+		return ruleBool(c <= 0)
+	case ">":
+		return ruleBool(c > 0)
+	}
+
+	return ruleBool(c >= 0)
+}
+
+// compareRuleValues compares numerically when both sides are numbers
+func compareRuleValues(a, b string) int {
+	x, errA := strconv.ParseFloat(normalizeNumber(a), 64)
+	y, errB := strconv.ParseFloat(normalizeNumber(b), 64)
+	if errA == nil && errB == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+
+	return strings.Compare(a, b)
+}
+
+type ruleParser struct {
+	tokens []string
+	pos    int
+}
+
+func parseRuleExpr(text string) (ruleNode, error) {
+	tokens, err := tokenizeRule(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	p := &ruleParser{tokens: tokens}
+	node, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.tokens[p.pos], text)
+	}
+
+	return node, nil
+}
+
+func (p *ruleParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+
+	return ""
+}
+
+func (p *ruleParser) next() string {
+	token := p.peek()
+	p.pos++
+
+	return token
+}
+
+func (p *ruleParser) or() (ruleNode, error) {
+	l, err := p.and()
+	for err == nil && (p.peek() == "or" || p.peek() == "||") {
+		p.next()
+		var r ruleNode
+		if r, err = p.and(); err == nil {
+			l = ruleBinary{"or", l, r}
+		}
+	}
+
+	return l, err
+}
+
+func (p *ruleParser) and() (ruleNode, error) {
+	l, err := p.not()
+	for err == nil && (p.peek() == "and" || p.peek() == "&&") {
+		p.next()
+		var r ruleNode
+		if r, err = p.not(); err == nil {
+			l = ruleBinary{"and", l, r}
+		}
+	}
+
+	return l, err
+}
+
+func (p *ruleParser) not() (ruleNode, error) {
+	if p.peek() == "not" || p.peek() == "!" {
+		p.next()
+		x, err := p.not()
+		return ruleNot{x}, err
+	}
+
+	return p.comparison()
+}
+
+func (p *ruleParser) comparison() (ruleNode, error) {
+	l, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.next()
+		r, err := p.operand()
+		return ruleBinary{op, l, r}, err
+	case "in":
+		p.next()
+		r, err := p.list()
+		return ruleBinary{"in", l, r}, err
+	case "not":
+		p.next()
+		if p.next() != "in" {
+			return nil, fmt.Errorf("expected in after not")
+		}
+		r, err := p.list()
+		return ruleBinary{"not in", l, r}, err
+	}
+
+	return l, nil
+}
+
+func (p *ruleParser) list() (ruleNode, error) {
+	if p.next() != "[" {
+		return nil, fmt.Errorf("expected [ after in")
+	}
+
+	var list ruleList
+	for p.peek() != "]" {
+		item, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+		if p.peek() == "," {
+			p.next()
+		}
+	}
+	p.next()
+
+	return list, nil
+}
+
+func (p *ruleParser) operand() (ruleNode, error) {
+	token := p.next()
+
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case token == "(":
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return node, nil
+	case strings.HasPrefix(token, `"`):
+		return ruleLiteral(strings.Trim(token, `"`)), nil
+	case strings.ContainsAny(token, "()[],<>=!"):
+		return nil, fmt.Errorf("unexpected %q", token)
+	}
+
+	return ruleWord(token), nil
+}
+
+func tokenizeRule(text string) ([]string, error) {
+	var tokens []string
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			j := i + 1
+			for j < len(runes) && runes[j] != '"' {
+				j++
+			}
+			if j == len(runes) {
+				return nil, fmt.Errorf("unterminated string in %q", text)
+			}
+			tokens = append(tokens, string(runes[i:j+1]))
+			i = j + 1
+		case strings.ContainsRune("()[],", r):
+			tokens = append(tokens, string(r))
+			i++
+		case strings.ContainsRune("<>=!&|", r):
+			j := i + 1
+			for j < len(runes) && strings.ContainsRune("=&|", runes[j]) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune(`()[],<>=!&|"`, runes[j]) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		}
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LayoutSchema describes a workbook format without any Go changes, read
+// from name.schema.json next to the workbook or from the schemas config:
+//
+//	{
+//	  "columns": "date=A,case=C,type=D,words=F,rate=G",
+//	  "header": ["日付", "ジョブ番号"],
+//	  "date_formats": ["2006/1/2", "1月2日"],
+//	  "skip": [{"field": "case", "pattern": "^(小計|合計)"}]
+//	}
+//
+// Columns are field=column pairs like --invoice-columns and --shuho-columns.
+// The entries start below the first row with every header text in it, and
+// a row whose field matches a skip pattern is left out without a parse
+// issue. Date formats are Go layouts, replacing the date patterns.
+type LayoutSchema struct {
+	Columns     string       `json:"columns"`
+	Header      []string     `json:"header"`
+	DateFormats []string     `json:"date_formats"`
+	Skip        []SchemaSkip `json:"skip"`
+}
+
+// SchemaSkip leaves out the rows whose field matches the pattern
+type SchemaSkip struct {
+	Field   string `json:"field"`
+	Pattern string `json:"pattern"`
+	re      *regexp.Regexp
+}
+
+// Schemas are the layout schema files of the workbooks without one next to them
+type Schemas struct {
+	Invoice string `json:"invoice"`
+	Shuho   string `json:"shuho"`
+}
+
+// invoiceSchema and shuhoSchema are the schemas of the workbooks being
+// verified, nil for the standard templates
+var invoiceSchema, shuhoSchema *LayoutSchema
+
+// schemaDateLayouts are the date formats of the schemas, getDate tries them first
+var schemaDateLayouts []string
+
+// schemaPath is name.schema.json next to the workbook when there is one,
+// the configured file otherwise
+func schemaPath(workbook, configured string) string {
+	if !strings.Contains(workbook, "://") && workbook != "-" {
+		path := strings.TrimSuffix(workbook, filepath.Ext(workbook)) + ".schema.json"
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return configured
+}
+
+func loadSchema(path string) (*LayoutSchema, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("schema %s doesn't exist", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var s LayoutSchema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range s.Skip {
+		if s.Skip[i].re, err = regexp.Compile(s.Skip[i].Pattern); err != nil {
+			return nil, fmt.Errorf("%s: skip %s: %w", path, s.Skip[i].Field, err)
+		}
+	}
+
+	return &s, nil
+}
+
+// applySchema sets the column mapping and the date pattern of the kind of
+// workbook from the schema
+func applySchema(s *LayoutSchema, columns *columnMapping, dateRe **regexp.Regexp) error {
+	if s.Columns != "" {
+		if err := columns.Set(s.Columns); err != nil {
+			return err
+		}
+	}
+	for _, skip := range s.Skip {
+		if columns.field(skip.Field) < 0 {
+			return fmt.Errorf("skip: unknown field %q, use %s", skip.Field, strings.Join(columns.fields, ", "))
+		}
+	}
+
+	if len(s.DateFormats) > 0 {
+		var patterns []string
+		for _, layout := range s.DateFormats {
+			patterns = append(patterns, layoutPattern(layout))
+		}
+		*dateRe = regexp.MustCompile(`^(` + strings.Join(patterns, "|") + `)$`)
+		schemaDateLayouts = append(schemaDateLayouts, s.DateFormats...)
+	}
+
+	return nil
+}
+
+// layoutTokens are the parts of Go date layouts the schemas use, longest first
+var layoutTokens = []struct{ token, pattern string }{
+	{"2006", `\d{4}`}, {"January", `[A-Za-z]+`}, {"Jan", `[A-Za-z]{3}`},
+	{"01", `\d{2}`}, {"02", `\d{2}`}, {"06", `\d{2}`}, {"_2", `[ \d]?\d`},
+	{"1", `\d{1,2}`}, {"2", `\d{1,2}`},
+}
+
+// layoutPattern is a regular expression matching the dates written in a Go layout
+func layoutPattern(layout string) string {
+	var b strings.Builder
+	for layout != "" {
+		matched := false
+		for _, t := range layoutTokens {
+			if strings.HasPrefix(layout, t.token) {
+				b.WriteString(t.pattern)
+				layout = layout[len(t.token):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			r := []rune(layout)[0]
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			layout = layout[len(string(r)):]
+		}
+	}
+
+	return b.String()
+}
+
+// parseSchemaDate reads a date in one of the schema formats, a date without
+// a year is in the year getDate gives m/d dates
+func parseSchemaDate(value string) (time.Time, bool) {
+	for _, layout := range schemaDateLayouts {
+		date, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(layout, "2006") && !strings.Contains(layout, "06") {
+			date = thisYearOrLastYear(date)
+		}
+		return date, true
+	}
+
+	return time.Time{}, false
+}
+
+// start is the index of the first row below the schema's header, 0
+// without a schema or header
+func (s *LayoutSchema) start(rows [][]string) int {
+	if s == nil || len(s.Header) == 0 {
+		return 0
+	}
+	for i, row := range rows {
+		found := 0
+		for _, text := range s.Header {
+			for _, cell := range row {
+				if strings.TrimSpace(cell) == text {
+					found++
+					break
+				}
+			}
+		}
+		if found == len(s.Header) {
+			return i + 1
+		}
+	}
+
+	//no header row, no entries
+	return len(rows)
+}
+
+// skips is true for a row, in field order, the schema leaves out
+func (s *LayoutSchema) skips(columns *columnMapping, row []string) bool {
+	if s == nil {
+		return false
+	}
+	for _, skip := range s.Skip {
+		if skip.re.MatchString(row[columns.field(skip.Field)]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loadSchemas applies the schemas of the shuho and invoice being verified
+func loadSchemas(shuho, invoice string) error {
+	invoiceSchema, shuhoSchema, schemaDateLayouts = nil, nil, nil
+	for _, kind := range []struct {
+		path    string
+		schema  **LayoutSchema
+		columns *columnMapping
+		dateRe  **regexp.Regexp
+	}{
+		{schemaPath(shuho, config.Schemas.Shuho), &shuhoSchema, shuhoColumnsf, &shuhoRowDateRe},
+		{schemaPath(invoice, config.Schemas.Invoice), &invoiceSchema, invoiceColumnsf, &invoiceDateRe},
+	} {
+		if kind.path == "" {
+			continue
+		}
+		s, err := loadSchema(kind.path)
+		if err != nil {
+			return err
+		}
+		if err := applySchema(s, kind.columns, kind.dateRe); err != nil {
+			return fmt.Errorf("%s: %w", kind.path, err)
+		}
+		*kind.schema = s
+	}
+
+	return nil
+}
+
+// keepSchemaState saves what loadSchemas changes, the returned func puts it
+// back for the daemon's next workbooks
+func keepSchemaState() func() {
+	shuhoCols, invoiceCols := append([]int(nil), shuhoColumnsf.cols...), append([]int(nil), invoiceColumnsf.cols...)
+	shuhoSet, invoiceSet := shuhoColumnsf.set, invoiceColumnsf.set
+	shuhoRe, invoiceRe := shuhoRowDateRe, invoiceDateRe
+	shuho, invoice, layouts := shuhoSchema, invoiceSchema, schemaDateLayouts
+
+	return func() {
+		shuhoColumnsf.cols, invoiceColumnsf.cols = shuhoCols, invoiceCols
+		shuhoColumnsf.set, invoiceColumnsf.set = shuhoSet, invoiceSet
+		shuhoRowDateRe, invoiceDateRe = shuhoRe, invoiceRe
+		shuhoSchema, invoiceSchema, schemaDateLayouts = shuho, invoice, layouts
+	}
+}
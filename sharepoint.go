@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Microsoft Graph and its token endpoint, tests point them at a local server
+var (
+	graphBaseURL = "https://graph.microsoft.com/v1.0"
+	loginBaseURL = "https://login.microsoftonline.com"
+)
+
+// isShareLink is true for SharePoint and OneDrive share links, which are
+// fetched through Graph instead of downloaded directly
+func isShareLink(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+
+	return u.Scheme == "https" && (strings.HasSuffix(host, ".sharepoint.com") || host == "1drv.ms" || host == "onedrive.live.com")
+}
+
+// shareLinkRequest asks Graph for the file behind a share link, see
+// https://learn.microsoft.com/graph/api/shares-get. The token is
+// GRAPH_ACCESS_TOKEN, or one for the app registration given by
+// AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET.
+func shareLinkRequest(link string) (*http.Request, error) {
+	token := os.Getenv("GRAPH_ACCESS_TOKEN")
+	if token == "" {
+		var err error
+		if token, err = graphAppToken(os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID"), os.Getenv("AZURE_CLIENT_SECRET")); err != nil {
+			return nil, err
+		}
+	}
+
+	shareID := "u!" + base64.RawURLEncoding.EncodeToString([]byte(link))
+	req, err := http.NewRequest(http.MethodGet, graphBaseURL+"/shares/"+shareID+"/driveItem/content", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return req, nil
+}
+
+// graphAppToken gets an access token with the client credentials grant
+func graphAppToken(tenant, clientID, secret string) (string, error) {
+	if tenant == "" || clientID == "" || secret == "" {
+		return "", fmt.Errorf("share links need GRAPH_ACCESS_TOKEN or AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET")
+	}
+
+	resp, err := remoteClient.PostForm(loginBaseURL+"/"+url.PathEscape(tenant)+"/oauth2/v2.0/token", url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {clientID},
+		"client_secret": {secret},
+		"scope":         {"https://graph.microsoft.com/.default"},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("token request: %s", resp.Status)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("token request: %s %s", resp.Status, token.ErrorDescription)
+	}
+
+	return token.AccessToken, nil
+}
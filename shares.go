@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strconv"
+	"time"
+)
+
+// typeShare is what one work type contributes to an invoice
+type typeShare struct {
+	eType   string
+	entries int
+	words   float64
+	revenue float64
+}
+
+// typeShares sums the entries by type, translations and checks first and
+// the unknown types together last
+func typeShares(entries []Entry) []typeShare {
+	shares := []typeShare{{eType: "翻訳"}, {eType: "英文チェック"}, {}}
+	for _, e := range entries {
+		i := 2
+		switch e.Type() {
+		case "翻訳":
+			i = 0
+		case "英文チェック":
+			i = 1
+		}
+		words, _ := strconv.ParseFloat(e.WordCount(), 64)
+		shares[i].entries++
+		shares[i].words += words
+		shares[i].revenue += lineAmount(e)
+	}
+	if shares[2].entries == 0 {
+		shares = shares[:2]
+	}
+
+	return shares
+}
+
+// yearToDateEntries are the invoice entries of the earlier months of the
+// calendar or --fiscal-year in the history and the ones of this invoice
+func yearToDateEntries(month string, invoiceEntries []Entry) []Entry {
+	entries := append([]Entry(nil), invoiceEntries...)
+	for _, record := range latestByMonth(history) {
+		if record.Month == month || !sameYear(record.Month, month) {
+			continue
+		}
+		for _, e := range record.Entries {
+			date, _ := time.Parse("2006-01-02", e.Date)
+			entries = append(entries, InvoiceEntry{IDate: date, ICaseNum: e.Case, IType: e.Type, IWordCount: e.Words, rate: e.Rate})
+		}
+	}
+
+	return entries
+}
+
+// printTypeShares prints the share of every type in the entries, the
+// revenue and the words and the entries themselves
+func printTypeShares(title string, entries []Entry) {
+	shares := typeShares(entries)
+	var total typeShare
+	for _, s := range shares {
+		total.entries += s.entries
+		total.words += s.words
+		total.revenue += s.revenue
+	}
+	percent := func(part, whole float64) float64 {
+		if whole == 0 {
+			return 0
+		}
+		return part / whole * 100
+	}
+
+	printer.Fprintf(out, "%s\n", title)
+	for _, s := range shares {
+		label := printer.Sprintf("other types")
+		if s.eType != "" {
+			label = typeLabel(s.eType)
+		}
+		printer.Fprintf(out, "  %s: revenue %.1f%%, words %.1f%%, entries %.1f%%\n", label,
+			percent(s.revenue, total.revenue), percent(s.words, total.words), percent(float64(s.entries), float64(total.entries)))
+	}
+}
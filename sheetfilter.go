@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sheetPatterns collects --only-sheets and --skip-sheets patterns, globs such
+// as 2024* or regular expressions between slashes such as /^20(23|24)-/.
+// A flag can be repeated or take a comma separated list.
+type sheetPatterns []string
+
+func (p *sheetPatterns) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *sheetPatterns) Set(value string) error {
+	patterns := strings.Split(value, ",")
+	//a regexp may contain commas itself
+	if _, ok := sheetRegexp(value); ok {
+		patterns = []string{value}
+	}
+
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		if re, ok := sheetRegexp(pattern); ok {
+			if _, err := regexp.Compile(re); err != nil {
+				return err
+			}
+		} else if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad sheet pattern %q: %w", pattern, err)
+		}
+		*p = append(*p, pattern)
+	}
+
+	return nil
+}
+
+func sheetRegexp(pattern string) (string, bool) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		return pattern[1 : len(pattern)-1], true
+	}
+
+	return "", false
+}
+
+func (p sheetPatterns) match(name string) bool {
+	for _, pattern := range p {
+		if re, ok := sheetRegexp(pattern); ok {
+			if regexp.MustCompile(re).MatchString(name) {
+				return true
+			}
+		} else if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+var onlySheetsf sheetPatterns
+var skipSheetsf sheetPatterns
+
+// sheetSelected is false for shuho sheets left out with --only-sheets or
+// --skip-sheets, or named after a month outside the period
+func sheetSelected(name string) bool {
+	if len(onlySheetsf) > 0 && !onlySheetsf.match(name) {
+		return false
+	}
+
+	return !skipSheetsf.match(name) && sheetInPeriod(name)
+}
+
+// shuhoPeriod is the first and last invoice date, set before the shuho is
+// parsed so sheets of other months can be skipped. Zero when unknown.
+var shuhoPeriod struct {
+	start, end time.Time
+}
+
+var allSheetsf bool
+
+// a year and month in a sheet name: 2023-06, 2023.6, 2023年6月, 202306, 2023-06-19
+var sheetMonthRe = regexp.MustCompile(`(\d{4})\s*[-/._年]?\s*(\d{1,2})`)
+
+// sheetMonth is the month a sheet name refers to, if it names one
+func sheetMonth(name string) (time.Time, bool) {
+	m := sheetMonthRe.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, false
+	}
+	year, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	if month < 1 || month > 12 {
+		return time.Time{}, false
+	}
+
+	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), true
+}
+
+// sheetInPeriod is false for sheets named after a month outside the period.
+// The month before it is kept: a weekly sheet named by its Monday can hold
+// the first days of the period.
+func sheetInPeriod(name string) bool {
+	if allSheetsf || shuhoPeriod.start.IsZero() {
+		return true
+	}
+	month, ok := sheetMonth(name)
+	if !ok {
+		return true
+	}
+
+	first := time.Date(shuhoPeriod.start.Year(), shuhoPeriod.start.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	last := time.Date(shuhoPeriod.end.Year(), shuhoPeriod.end.Month(), 1, 0, 0, 0, 0, time.UTC)
+	//the overdue check looks further back
+	if lookback := now().AddDate(0, 0, -overdueLookbackf); overdueDaysf > 0 && lookback.Before(first) {
+		first = time.Date(lookback.Year(), lookback.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return !month.Before(first) && !month.After(last)
+}
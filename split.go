@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxSplitParts bounds the shuho rows tried for one invoice entry, the
+// combinations grow exponentially with them
+const maxSplitParts = 16
+
+// splitDelivery is an invoice entry billing the work of several shuho
+// entries at once, a case delivered in parts and invoiced when finished
+type splitDelivery struct {
+	invoice Entry
+	parts   []Entry
+}
+
+// findSplitDeliveries pairs every invoice entry no shuho entry matches with
+// shuho entries of the same case and type, themselves on no invoice row,
+// whose word counts add up to the invoiced ones. Each shuho entry goes to
+// one invoice entry at most, the earliest.
+func findSplitDeliveries(scopedShuho, ientries []Entry) []splitDelivery {
+	logged := make(map[string]bool)
+	for _, se := range scopedShuho {
+		logged[se.signature()] = true
+	}
+	invoiced := make(map[string]bool)
+	for _, ie := range ientries {
+		invoiced[ie.signature()] = true
+	}
+
+	unmatched := make(map[string][]Entry)
+	for _, se := range scopedShuho {
+		if !invoiced[se.signature()] {
+			key := matchCase(se.CaseNum()) + " " + se.Type()
+			unmatched[key] = append(unmatched[key], se)
+		}
+	}
+
+	var splits []splitDelivery
+	for _, ie := range ientries {
+		words, err := strconv.Atoi(ie.WordCount())
+		key := matchCase(ie.CaseNum()) + " " + ie.Type()
+		if err != nil || logged[ie.signature()] || len(unmatched[key]) < 2 {
+			continue
+		}
+		candidates := unmatched[key]
+		if len(candidates) > maxSplitParts {
+			candidates = candidates[:maxSplitParts]
+		}
+		if parts := partsSumming(candidates, words); parts != nil {
+			splits = append(splits, splitDelivery{ie, parts})
+			unmatched[key] = without(unmatched[key], parts)
+		}
+	}
+
+	return splits
+}
+
+// partsSumming is the first combination of two or more candidates whose
+// word counts add up to words, nil when there's none
+func partsSumming(candidates []Entry, words int) []Entry {
+	counts := make([]int, len(candidates))
+	for i, c := range candidates {
+		counts[i], _ = strconv.Atoi(c.WordCount())
+	}
+
+	var picked []int
+	var search func(from, left int) bool
+	search = func(from, left int) bool {
+		if left == 0 {
+			return len(picked) >= 2
+		}
+		for i := from; i < len(candidates); i++ {
+			if counts[i] <= 0 || counts[i] > left {
+				continue
+			}
+			picked = append(picked, i)
+			if search(i+1, left-counts[i]) {
+				return true
+			}
+			picked = picked[:len(picked)-1]
+		}
+		return false
+	}
+	if !search(0, words) {
+		return nil
+	}
+
+	parts := make([]Entry, 0, len(picked))
+	for _, i := range picked {
+		parts = append(parts, candidates[i])
+	}
+
+	return parts
+}
+
+func without(entries, remove []Entry) []Entry {
+	var kept []Entry
+	for _, e := range entries {
+		removed := false
+		for _, r := range remove {
+			if e.Location() == r.Location() {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			kept = append(kept, e)
+		}
+	}
+
+	return kept
+}
+
+// describeParts lists the shuho entries of a split delivery, 1,200 at
+// shuho.xlsx '2023-06'!A5 + 800 at shuho.xlsx '2023-06'!A9
+func describeParts(parts []Entry) string {
+	var described []string
+	for _, part := range parts {
+		described = append(described, printer.Sprintf("%s at %s", part.WordCount(), part.Location()))
+	}
+
+	return strings.Join(described, " + ")
+}
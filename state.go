@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// runState remembers the successful runs by the checksums of their inputs,
+// so --changed-only can skip verifying the same workbooks again
+type runState struct {
+	Runs map[string]stateEntry `json:"runs"`
+}
+
+type stateEntry struct {
+	Time    string    `json:"time"`
+	Shuho   inputFile `json:"shuho"`
+	Invoice inputFile `json:"invoice"`
+	Pretax  float64   `json:"pretax"`
+}
+
+var changedOnlyf bool
+var statef string
+
+func defaultStatePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "verifyshuho", "state.json")
+}
+
+// checkFlagSet is the flag set checkFlags registered on last and
+// checkFlagNames the flags it added, their values decide what passes
+var checkFlagSet *flag.FlagSet
+var checkFlagNames []string
+
+// stateKey is the same for the same shuho and invoice verified by the same
+// version with the same settings
+func stateKey(shuho, invoice inputFile) string {
+	return versionString() + " " + settingsChecksum() + " " + shuho.SHA256 + " " + invoice.SHA256
+}
+
+// settingsChecksum is the SHA-256 of the loaded config and ignore file and
+// of the check flags, a stricter run than the cached one verifies again
+func settingsChecksum() string {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	enc.Encode(config)
+	enc.Encode(ignoreRules)
+	enc.Encode(daysOff)
+	if checkFlagSet != nil {
+		for _, name := range checkFlagNames {
+			fmt.Fprintf(h, "%s=%s\n", name, checkFlagSet.Lookup(name).Value)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadState reads path, a state file that doesn't exist yet is empty
+func loadState(path string) (runState, error) {
+	state := runState{Runs: make(map[string]stateEntry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, err
+	}
+	if state.Runs == nil {
+		state.Runs = make(map[string]stateEntry)
+	}
+
+	return state, nil
+}
+
+func saveState(path string, state runState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// runStats are the metrics of a verification, printed by the stats command
+type runStats struct {
+	Shuho          string `json:"shuho"`
+	Invoice        string `json:"invoice"`
+	ShuhoEntries   int    `json:"shuho_entries"`
+	InvoiceEntries int    `json:"invoice_entries"`
+	RowsSkipped    int    `json:"rows_skipped"`
+	ParseErrors    int    `json:"parse_errors"`
+	// the cancelled shuho entries of the invoiced period
+	Cancelled cancelledStats `json:"cancelled"`
+	// check id -> findings of any severity, 0 for the checks that found nothing
+	Findings map[string]int `json:"findings"`
+	Errors   int            `json:"errors"`
+	Warnings int            `json:"warnings"`
+	// findings dropped by the ignore file
+	Ignored    int     `json:"ignored"`
+	ExitStatus int     `json:"exit_status"`
+	Pretax     float64 `json:"pretax"`
+	// seconds spent reading the workbooks, running the checks and in total
+	ParseSeconds float64 `json:"parse_seconds"`
+	CheckSeconds float64 `json:"check_seconds"`
+	TotalSeconds float64 `json:"total_seconds"`
+}
+
+// cancelledStats sum up cancelled jobs, the prep work that goes unpaid
+type cancelledStats struct {
+	Entries          int `json:"entries"`
+	TranslationWords int `json:"translation_words"`
+	CheckWords       int `json:"check_words"`
+}
+
+func sumCancelled(entries []Entry) cancelledStats {
+	var s cancelledStats
+	for _, e := range entries {
+		words, _ := strconv.Atoi(e.WordCount())
+		s.Entries++
+		switch e.Type() {
+		case "翻訳":
+			s.TranslationWords += words
+		case "英文チェック":
+			s.CheckWords += words
+		}
+	}
+
+	return s
+}
+
+// stats is filled in by verify as it goes
+var stats = runStats{Findings: make(map[string]int)}
+
+var statsJSONf bool
+
+func (s *runStats) addResults(results []CheckResult) {
+	for _, result := range results {
+		s.Findings[result.ID] = len(result.Findings)
+		for _, finding := range result.Findings {
+			switch finding.Severity {
+			case SeverityError:
+				s.Errors++
+			case SeverityWarning:
+				s.Warnings++
+			}
+		}
+	}
+}
+
+// countErrors is the number of errors joined in err
+func countErrors(err error) int {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return len(joined.Unwrap())
+	}
+	if err != nil {
+		return 1
+	}
+
+	return 0
+}
+
+// statsCommand verifies the workbooks without printing the report or
+// writing anything a verification records, and prints its metrics instead,
+// for scheduled jobs feeding a metrics system. The exit status is the
+// verification's.
+func statsCommand(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	fs.BoolVar(&statsJSONf, "json", false, "print the metrics as one JSON object")
+	checkFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fmt.Fprintln(out, "\033[1;31mERROR Usage:\033[0m ./verifyshuho stats [--json] [OPTIONS] <Shuho.xlsx> <Invoice.xlsx>")
+		fs.SetOutput(out)
+		fs.PrintDefaults()
+		return 2
+	}
+
+	start := time.Now()
+	if deterministicf {
+		now = func() time.Time { return deterministicNow }
+	}
+	stats = runStats{Findings: make(map[string]int)}
+	stats.ExitStatus = collectStats(fs.Arg(0), fs.Arg(1))
+	stats.TotalSeconds = time.Since(start).Seconds()
+	if err := writeStats(out, stats); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	return stats.ExitStatus
+}
+
+// collectStats parses and checks the workbooks into stats and returns the
+// exit status, problems reading them go to stderr
+func collectStats(shuhoName, invoiceName string) int {
+	if err := loadSettings(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := loadSchemas(shuhoName, invoiceName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	fshuho, err := openWorkbook(shuhoName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer fshuho.Close()
+	finvoice, err := openWorkbook(invoiceName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer finvoice.Close()
+
+	parseIssues, cancelledEntries = nil, nil
+	shuhoPeriod.start, shuhoPeriod.end = time.Time{}, time.Time{}
+	shuhoEntries, invoiceEntries, parseErr := parseWorkbooks(fshuho, finvoice)
+	if len(shuhoEntries) == 0 || len(invoiceEntries) == 0 {
+		return 2
+	}
+	stats.Cancelled = sumCancelled(getScopedShuho(cancelledEntries, invoiceEntries))
+
+	runChecks(shuhoEntries, invoiceEntries)
+	if parseErr != nil || runFailed(stats.Errors, stats.Warnings) {
+		return 1
+	}
+
+	return 0
+}
+
+// writeStats prints s as JSON with --json, otherwise as name value lines
+func writeStats(w io.Writer, s runStats) error {
+	if statsJSONf {
+		return json.NewEncoder(w).Encode(s)
+	}
+
+	ids := make([]string, 0, len(s.Findings))
+	for id := range s.Findings {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	fmt.Fprintf(w, "shuho_entries %d\ninvoice_entries %d\nrows_skipped %d\nparse_errors %d\n", s.ShuhoEntries, s.InvoiceEntries, s.RowsSkipped, s.ParseErrors)
+	fmt.Fprintf(w, "cancelled_entries %d\ncancelled_translation_words %d\ncancelled_check_words %d\n", s.Cancelled.Entries, s.Cancelled.TranslationWords, s.Cancelled.CheckWords)
+	for _, id := range ids {
+		fmt.Fprintf(w, "findings{check=%q} %d\n", id, s.Findings[id])
+	}
+	fmt.Fprintf(w, "errors %d\nwarnings %d\nignored %d\nexit_status %d\npretax %g\n", s.Errors, s.Warnings, s.Ignored, s.ExitStatus, s.Pretax)
+	_, err := fmt.Fprintf(w, "parse_seconds %g\ncheck_seconds %g\ntotal_seconds %g\n", s.ParseSeconds, s.CheckSeconds, s.TotalSeconds)
+
+	return err
+}
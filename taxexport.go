@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// taxColumns are the headings of the tax export, close to what the import
+// screens of freee, やよいの青色申告 and マネーフォワード ask for. 経費 is left
+// for the expenses of the month, the history doesn't know them.
+var taxColumns = []string{"月", "取引日", "取引先", "売上金額", "源泉徴収税額", "差引入金額", "経費", "摘要"}
+
+// withholdingTax is the 源泉徴収 the agency takes off a payment for
+// translation work: 10.21% of up to a million yen and 20.42% of the rest,
+// rounded down to the yen
+func withholdingTax(gross float64) float64 {
+	if gross <= 0 {
+		return 0
+	}
+	tax := math.Min(gross, 1000000) * 0.1021
+	if gross > 1000000 {
+		tax += (gross - 1000000) * 0.2042
+	}
+
+	return math.Floor(tax + 1e-6)
+}
+
+// writeTaxExport writes a row per recorded month of the calendar or
+// --fiscal-year year and a 合計 row, amounts in whole yen
+func writeTaxExport(w io.Writer, records []HistoryRecord, year int, client string) error {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	cw.Write(taxColumns)
+
+	var gross, withheld float64
+	for _, record := range latestByMonth(records) {
+		if yearOf(record.Month) != year {
+			continue
+		}
+		month, err := time.Parse("2006-01", record.Month)
+		if err != nil {
+			continue
+		}
+		pretax := math.Round(record.Pretax)
+		tax := withholdingTax(pretax)
+		gross += pretax
+		withheld += tax
+		cw.Write([]string{
+			record.Month,
+			month.AddDate(0, 1, -1).Format("2006/01/02"),
+			client,
+			strconv.FormatFloat(pretax, 'f', 0, 64),
+			strconv.FormatFloat(tax, 'f', 0, 64),
+			strconv.FormatFloat(pretax-tax, 'f', 0, 64),
+			"",
+			"翻訳料 " + record.Month,
+		})
+	}
+	cw.Write([]string{"合計", "", client, strconv.FormatFloat(gross, 'f', 0, 64), strconv.FormatFloat(withheld, 'f', 0, 64), strconv.FormatFloat(gross-withheld, 'f', 0, 64), "", ""})
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// taxExportCommand writes the tax export of a year from the history
+func taxExportCommand(args []string) int {
+	fs := flag.NewFlagSet("tax-export", flag.ExitOnError)
+	year := fs.Int("year", now().Year()-1, "year to export, the fiscal year starting in April of it with --fiscal-year")
+	client := fs.String("client", "", "name of the agency for the 取引先 column")
+	encoding := fs.String("encoding", "shift_jis", "shift_jis, which most tax software imports, or utf-8")
+	output := fs.String("o", "", "write to `file` instead of stdout")
+	historyPath := fs.String("history", "", "history file written by --history (default history_file from the config)")
+	configPath := fs.String("config", "", "config file (default verifyshuho/config.json in the user config directory)")
+	fs.BoolVar(&fiscalYearf, "fiscal-year", false, "export the April to March fiscal year")
+	fs.Parse(args)
+
+	var err error
+	if config, err = loadConfig(*configPath); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	if *historyPath == "" {
+		*historyPath = config.HistoryFile
+	}
+	if *historyPath == "" || (*encoding != "shift_jis" && *encoding != "utf-8") {
+		fmt.Fprintln(out, "\033[1;31mERROR Usage:\033[0m ./verifyshuho tax-export [--year 2024] [--fiscal-year] [--client name] [--encoding shift_jis|utf-8] [-o file] --history history.jsonl")
+		return 2
+	}
+	records, err := loadHistory(*historyPath)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+			return 2
+		}
+		defer file.Close()
+		w = file
+	}
+	if *encoding == "shift_jis" {
+		w = japanese.ShiftJIS.NewEncoder().Writer(w)
+	}
+
+	if err := writeTaxExport(w, records, *year, *client); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// teamResult is the outcome for one translator of the team
+type teamResult struct {
+	author, invoice  string
+	entries          int
+	errors, warnings int
+	pretax           float64
+	status           int
+}
+
+// verifyTeam verifies every invoice in dir against the rows of its author in
+// the shared shuho, and prints a pass/fail summary per translator at the end.
+// Translators with rows in the invoiced months but no invoice fail too.
+func verifyTeam(fshuho Workbook, shuhoInput inputFile, dir string) int {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	var names []string
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") || strings.HasPrefix(file.Name(), "~$") {
+			continue
+		}
+		if archiveInvoiceExts[strings.ToLower(filepath.Ext(file.Name()))] {
+			names = append(names, filepath.Join(dir, file.Name()))
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m No invoices in %s\n", dir)
+		return 2
+	}
+
+	var results []teamResult
+	var months []time.Time
+	var shuhoEntries []Entry
+	worst := 0
+	for _, name := range names {
+		printer.Fprintf(out, "\n==== %s ====\n", name)
+
+		status, author, entries := verifyTeamMember(fshuho, shuhoInput, name)
+		results = append(results, teamResult{author, name, stats.InvoiceEntries, stats.Errors, stats.Warnings, stats.Pretax, status})
+		shuhoEntries = append(shuhoEntries, entries...)
+		if !shuhoPeriod.start.IsZero() {
+			months = append(months, shuhoPeriod.start, shuhoPeriod.end)
+		}
+		if status > worst {
+			worst = status
+		}
+	}
+
+	//whoever worked in the invoiced months has to have sent an invoice
+	for _, author := range missingInvoices(shuhoEntries, results, months) {
+		results = append(results, teamResult{author: author, status: 1})
+		if worst < 1 {
+			worst = 1
+		}
+	}
+
+	printTeamSummary(results)
+
+	return worst
+}
+
+// verifyTeamMember verifies the invoice at name against the rows of its
+// author, see verifyAuthored
+func verifyTeamMember(fshuho Workbook, shuhoInput inputFile, name string) (int, string, []Entry) {
+	stats = runStats{Findings: make(map[string]int)}
+
+	finvoice, err := openWorkbook(name)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 2, "", nil
+	}
+	defer finvoice.Close()
+	invoiceInput, err := newInputFile(name)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2, "", nil
+	}
+
+	return verifyAuthored(fshuho, finvoice, shuhoInput, invoiceInput, true)
+}
+
+// invoiceAuthor is the shuho author an invoice is from: the translator of its
+// header, or else the author whose name is in the file name, e.g.
+// tanaka_2023-06.xlsx. Either is compared case-insensitively with the
+// authors of the shuho, the empty string when none of them matches.
+func invoiceAuthor(name string, header invoiceHeader, shuhoEntries []Entry) string {
+	var authors []string
+	seen := make(map[string]bool)
+	for _, e := range shuhoEntries {
+		if se, ok := e.(ShuhoEntry); ok && se.SAuthor != "" && !seen[se.SAuthor] {
+			seen[se.SAuthor] = true
+			authors = append(authors, se.SAuthor)
+		}
+	}
+	//the longest first so that a name containing another one wins
+	sort.Slice(authors, func(i, j int) bool { return len(authors[i]) > len(authors[j]) })
+
+	if translator, ok := header["translator"]; ok {
+		for _, author := range authors {
+			if strings.EqualFold(strings.TrimSpace(translator.value), author) {
+				return author
+			}
+		}
+	}
+	base := strings.ToLower(strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)))
+	for _, author := range authors {
+		if strings.Contains(base, strings.ToLower(author)) {
+			return author
+		}
+	}
+	return ""
+}
+
+// authoredBy is the entries of the shuho from author
+func authoredBy(entries []Entry, author string) []Entry {
+	var authored []Entry
+	for _, e := range entries {
+		if se, ok := e.(ShuhoEntry); ok && se.SAuthor == author {
+			authored = append(authored, e)
+		}
+	}
+	return authored
+}
+
+// missingInvoices is the authors with shuho rows in the months of the
+// invoices who aren't the author of any of them
+func missingInvoices(shuhoEntries []Entry, results []teamResult, months []time.Time) []string {
+	invoiced := make(map[string]bool)
+	for _, r := range results {
+		invoiced[r.author] = true
+	}
+	inMonths := func(date time.Time) bool {
+		for _, month := range months {
+			if date.Year() == month.Year() && date.Month() == month.Month() {
+				return true
+			}
+		}
+		return false
+	}
+
+	var missing []string
+	for _, e := range shuhoEntries {
+		se, ok := e.(ShuhoEntry)
+		if !ok || se.SAuthor == "" || invoiced[se.SAuthor] || !inMonths(se.SDate) {
+			continue
+		}
+		invoiced[se.SAuthor] = true
+		missing = append(missing, se.SAuthor)
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func printTeamSummary(results []teamResult) {
+	colorize(ColorGreen, translate("\n** Per translator: "))
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", translate("Author"), translate("Invoice"), translate("Entries"), translate("Errors"), translate("Warnings"), translate("Pre-tax total"), translate("Result"))
+	for _, r := range results {
+		author, result := r.author, translate("passed")
+		if author == "" {
+			author = "?"
+		}
+		switch {
+		case r.invoice == "":
+			printer.Fprintf(w, "%s\t%s\t\t\t\t\t%s\n", author, translate("none"), translate("no invoice"))
+			continue
+		case r.status != 0:
+			result = translate("failed")
+		}
+		printer.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n", author, filepath.Base(r.invoice), r.entries, r.errors, r.warnings, formatYen(r.pretax), result)
+	}
+	w.Flush()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+)
+
+// daysf lists the shuho words of every day of the period
+var daysf bool
+
+// printDailyThroughput sums the scoped shuho entries per calendar day
+func printDailyThroughput(shuho, invoice []Entry) {
+	tallies := tallyShuho(shuho, invoice, func(se ShuhoEntry) string { return se.SDate.Format("2006-01-02") })
+	sort.Slice(tallies, func(i, j int) bool { return tallies[i].name < tallies[j].name })
+
+	printTallies("\n** Per day: ", "Day", tallies)
+}
+
+// ensureDailyCapacity warns about days of the shuho with more words of a
+// type than config.DailyCapacity allows, more than anyone gets done in a day
+// is usually a word count typed with a digit too many
+func ensureDailyCapacity(shuho, invoice []Entry, capacity map[string]int) []Finding {
+	if len(capacity) == 0 {
+		return nil
+	}
+
+	type day struct {
+		date, eType string
+	}
+	words := make(map[day]int)
+	last := make(map[day]Entry)
+	var days []day
+	for _, e := range getScopedShuho(shuho, invoice) {
+		if _, ok := capacity[e.Type()]; !ok {
+			continue
+		}
+		d := day{e.Date().Format("2006-01-02"), e.Type()}
+		if _, ok := last[d]; !ok {
+			days = append(days, d)
+		}
+		n, _ := strconv.Atoi(e.WordCount())
+		words[d] += n
+		last[d] = e
+	}
+
+	var findings []Finding
+	for _, d := range days {
+		if words[d] <= capacity[d.eType] {
+			continue
+		}
+		findings = append(findings, Finding{
+			Message:  printer.Sprintf("%d words of %s on %s, more than the daily capacity of %d: %s", words[d], typeLabel(d.eType), d.date, capacity[d.eType], last[d].String()),
+			Entry:    last[d],
+			Severity: SeverityWarning,
+		})
+	}
+
+	return findings
+}
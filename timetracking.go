@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeTrackingf is a Toggl or Clockify CSV export of the tracked time
+var timeTrackingf string
+
+// trackedHours are the hours of the time tracking export per day, nil
+// leaves the tracked-time check out
+var trackedHours map[string]float64
+
+// untrackedWords is the volume of a day that can't have been done without
+// tracking any time, smaller days are easily forgotten
+const untrackedWords = 1000
+
+// trackingDateLayouts are the date formats of the Toggl and Clockify exports
+var trackingDateLayouts = []string{"2006-01-02", "01/02/2006", "2006/01/02", "02.01.2006"}
+
+// loadTimeTracking sums the hours of a Toggl or Clockify detailed CSV
+// export per start date. Toggl names the columns "Start date" and
+// "Duration", Clockify "Start Date" and "Duration (decimal)" or
+// "Duration (h)".
+func loadTimeTracking(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s: empty time tracking export", path)
+	}
+
+	dateCol, durationCol, decimal := -1, -1, false
+	for i, name := range rows[0] {
+		//Excel saved exports start with a byte order mark
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) {
+		case "start date":
+			dateCol = i
+		case "duration (decimal)":
+			durationCol, decimal = i, true
+		case "duration", "duration (h)":
+			if !decimal {
+				durationCol = i
+			}
+		}
+	}
+	if dateCol < 0 || durationCol < 0 {
+		return nil, fmt.Errorf("%s: no Start date and Duration columns, not a Toggl or Clockify export", path)
+	}
+
+	hours := make(map[string]float64)
+	for line, row := range rows[1:] {
+		if len(row) <= dateCol || len(row) <= durationCol {
+			continue
+		}
+		date, err := parseTrackingDate(row[dateCol])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line+2, err)
+		}
+		var h float64
+		if decimal {
+			h, err = strconv.ParseFloat(strings.TrimSpace(row[durationCol]), 64)
+		} else {
+			h, err = parseTrackedDuration(row[durationCol])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line+2, err)
+		}
+		hours[date.Format("2006-01-02")] += h
+	}
+
+	return hours, nil
+}
+
+func parseTrackingDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range trackingDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unknown date %q", value)
+}
+
+// parseTrackedDuration reads the h:mm:ss or h:mm durations of the exports as hours
+func parseTrackedDuration(value string) (float64, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("unknown duration %q", value)
+	}
+	var hours float64
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, fmt.Errorf("unknown duration %q", value)
+		}
+		hours += float64(n) / [3]float64{1, 60, 3600}[i]
+	}
+
+	return hours, nil
+}
+
+// ensureTimeTracked warns about shuho days with untrackedWords or more and
+// no time tracked on them, the entry is likely on the wrong date
+func ensureTimeTracked(shuho, invoice []Entry, tracked map[string]float64) []Finding {
+	if tracked == nil {
+		return nil
+	}
+
+	words := make(map[string]int)
+	last := make(map[string]Entry)
+	var days []string
+	for _, e := range getScopedShuho(shuho, invoice) {
+		day := e.Date().Format("2006-01-02")
+		if _, ok := last[day]; !ok {
+			days = append(days, day)
+		}
+		n, _ := strconv.Atoi(e.WordCount())
+		words[day] += n
+		last[day] = e
+	}
+
+	var findings []Finding
+	for _, day := range days {
+		if words[day] < untrackedWords || tracked[day] > 0 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Message:  printer.Sprintf("%d words on %s but no time tracked: %s", words[day], day, last[day].String()),
+			Entry:    last[day],
+			Severity: SeverityWarning,
+		})
+	}
+
+	return findings
+}
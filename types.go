@@ -0,0 +1,63 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TypeLabels are the words a set of sheets uses for the work types, older
+// sheets written in English say:
+//
+//	"type_labels": [{"sheets": "^2021", "labels": {"Translation": "翻訳", "Check": "英文チェック"}}]
+//
+// Sheets is a regular expression on the sheet name and Years the years of the
+// entry dates, either one empty matches every sheet or year.
+type TypeLabels struct {
+	Sheets string            `json:"sheets"`
+	Years  []int             `json:"years"`
+	Labels map[string]string `json:"labels"`
+}
+
+func (t TypeLabels) matches(sheet string, date time.Time) bool {
+	if t.Sheets != "" {
+		if ok, _ := regexp.MatchString(t.Sheets, sheet); !ok {
+			return false
+		}
+	}
+	if len(t.Years) == 0 {
+		return true
+	}
+	for _, year := range t.Years {
+		if year == date.Year() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// englishTypeLabels are read as the Japanese types on every sheet, the
+// agency's English template uses them
+var englishTypeLabels = map[string]string{
+	"translation":   "翻訳",
+	"check":         "英文チェック",
+	"english check": "英文チェック",
+}
+
+// canonicalType is the type the checks know for the label of a row, from the
+// first matching type_labels set or the English labels, other labels are
+// kept as they are for the unknown-type check
+func canonicalType(label, sheet string, date time.Time) string {
+	key := strings.TrimSpace(label)
+	for _, set := range config.TypeLabels {
+		if eType, ok := set.Labels[key]; ok && set.matches(sheet, date) {
+			return eType
+		}
+	}
+	if eType, ok := englishTypeLabels[strings.ToLower(key)]; ok {
+		return eType
+	}
+
+	return label
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// auditUnbilledCommand checks every sheet of a shuho against any number of
+// invoices and lists the shuho entries none of them bills, whatever month
+// they are from
+func auditUnbilledCommand(args []string) int {
+	fs := flag.NewFlagSet("audit-unbilled", flag.ExitOnError)
+	checkFlags(fs)
+	fs.Parse(args)
+
+	if fs.NArg() < 2 {
+		fmt.Fprintln(out, "\033[1;31mERROR Usage:\033[0m ./verifyshuho audit-unbilled [OPTIONS] <shuho.xlsx> <invoice.xlsx>...")
+		return 2
+	}
+	//the case aliases, excluded cases, type labels and layouts of the years audited
+	if err := loadSettings(); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	allSheetsf = true
+	shuhoPeriod.start, shuhoPeriod.end = time.Time{}, time.Time{}
+	parseIssues, cancelledEntries = nil, nil
+
+	fshuho, err := openWorkbook(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	defer fshuho.Close()
+	shuho, parseErr := parseShuho(fshuho)
+	shuho = excludeCases(shuho, config.ExcludedCases)
+
+	var invoices []Entry
+	var count int
+	for _, pattern := range fs.Args()[1:] {
+		//patterns the shell didn't expand, on Windows say
+		paths, _ := filepath.Glob(pattern)
+		if len(paths) == 0 {
+			paths = []string{pattern}
+		}
+		for _, path := range paths {
+			finvoice, err := openWorkbook(path)
+			if err != nil {
+				fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+				return 2
+			}
+			entries, err := parseInvoice(finvoice)
+			finvoice.Close()
+			if err != nil {
+				fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+			}
+			invoices = append(invoices, entries...)
+			count++
+		}
+	}
+
+	unbilled := unbilledEntries(shuho, invoices)
+	for _, e := range unbilled {
+		printer.Fprintf(out, "\033[1;31mUNBILLED:\033[0m %s at %s\n", e.String(), link(e.Location().String(), e.Location()))
+	}
+	printParseIssues(parseErr, parseIssues)
+
+	if len(unbilled) > 0 {
+		printer.Fprintf(out, "\n%d of %d shuho entries are on none of the %d invoices\n", len(unbilled), len(matchable(shuho)), count)
+		return 1
+	}
+	showCheckSuccess(printer.Sprintf("All %d shuho entries are on one of the %d invoices", len(matchable(shuho)), count))
+
+	return 0
+}
+
+// unbilledEntries are the shuho entries left once every invoice entry has
+// taken one shuho entry of its signature, oldest first
+func unbilledEntries(shuho, invoices []Entry) []Entry {
+	billed := make(map[string]int)
+	for _, ie := range matchable(invoices) {
+		billed[ie.signature()]++
+	}
+
+	entries := append([]Entry(nil), matchable(shuho)...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date().Before(entries[j].Date()) })
+
+	var unbilled []Entry
+	for _, se := range entries {
+		if billed[se.signature()] > 0 {
+			billed[se.signature()]--
+			continue
+		}
+		unbilled = append(unbilled, se)
+	}
+
+	return unbilled
+}
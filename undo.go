@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// fixSession is one applied --fix, a line of the fix journal
+type fixSession struct {
+	Time   string `json:"time"`
+	Kind   string `json:"kind"`
+	Source string `json:"source"`
+	Output string `json:"output"`
+	// the copy of what was at Output before, empty when it was created or
+	// written with --no-backup
+	Backup  string `json:"backup,omitempty"`
+	Created bool   `json:"created"`
+	// the checksum of Output as written, undo leaves later edits alone
+	SHA256  string   `json:"sha256"`
+	Changes []string `json:"changes"`
+}
+
+var fixJournalf string
+
+func fixJournalPath() string {
+	if fixJournalf != "" {
+		return fixJournalf
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "fixes.jsonl"
+	}
+
+	return filepath.Join(dir, "verifyshuho", "fixes.jsonl")
+}
+
+func appendFixSession(path string, session fixSession) error {
+	line, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// loadFixSessions reads the journal, oldest first
+func loadFixSessions(path string) ([]fixSession, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sessions []fixSession
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var session fixSession
+		if err := json.Unmarshal(scanner.Bytes(), &session); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, scanner.Err()
+}
+
+func saveFixSessions(path string, sessions []fixSession) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, session := range sessions {
+		if err := enc.Encode(session); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	return f.Close()
+}
+
+// undoCommand restores the workbook the last --fix wrote to what it was
+// before, and drops the fix from the journal
+func undoCommand(args []string) int {
+	fs := flag.NewFlagSet("undo", flag.ExitOnError)
+	fs.StringVar(&fixJournalf, "journal", "", "the fix journal (in the user cache directory by default)")
+	force := fs.Bool("force", false, "undo even when the workbook changed since the fix")
+	fs.Parse(args)
+
+	path := fixJournalPath()
+	sessions, err := loadFixSessions(path)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	if len(sessions) == 0 {
+		printer.Fprintf(out, "No fixes to undo in %s\n", path)
+		return 1
+	}
+	last := sessions[len(sessions)-1]
+
+	//a workbook deleted since the fix can only be dropped from the journal, or restored from its backup
+	sum, err := fileChecksum(last.Output)
+	missing := errors.Is(err, os.ErrNotExist)
+	if err != nil && !missing {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+	if missing && !*force {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m %s is gone since the fix of %s, undo with --force to drop the fix\n", last.Output, last.Time)
+		return 1
+	}
+	if !missing && sum != last.SHA256 && !*force {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m %s changed since the fix of %s, undo with --force to lose the changes\n", last.Output, last.Time)
+		return 1
+	}
+
+	err = nil
+	switch {
+	case last.Created:
+		if !missing {
+			err = os.Remove(last.Output)
+		}
+	case last.Backup != "":
+		err = copyFile(last.Backup, last.Output)
+	case !missing:
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m The fix of %s overwrote %s without a backup\n", last.Time, last.Output)
+		return 1
+	}
+	if err == nil {
+		err = saveFixSessions(path, sessions[:len(sessions)-1])
+	}
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	printer.Fprintf(out, "Undid the %s fix of %s on %s:\n", last.Kind, last.Time, last.Output)
+	for _, change := range last.Changes {
+		fmt.Fprintln(out, "  "+change)
+	}
+	switch {
+	case last.Created && missing:
+		printer.Fprintf(out, "%s the fix created is gone already\n", last.Output)
+	case last.Created:
+		printer.Fprintf(out, "Removed %s, the fix created it\n", last.Output)
+	case last.Backup != "":
+		printer.Fprintf(out, "Restored %s from %s\n", last.Output, last.Backup)
+	default:
+		printer.Fprintf(out, "Dropped the fix of the missing %s\n", last.Output)
+	}
+
+	return 0
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	outFile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(outFile, in); err != nil {
+		outFile.Close()
+		return err
+	}
+
+	return outFile.Close()
+}
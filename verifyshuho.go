@@ -7,18 +7,19 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"math"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/xuri/excelize/v2"
-	"golang.org/x/text/language"
-	"golang.org/x/text/message"
 )
 
 type Color string
@@ -33,13 +34,53 @@ const (
 )
 
 func colorize(color Color, message string) {
-	fmt.Println(string(color), message, string(ColorReset))
+	fmt.Fprintln(out, string(color), message, string(ColorReset))
+}
+
+// color codes and the OSC 8 hyperlinks around locations
+var ansiEscapeRe = regexp.MustCompile("\x1b\\[[0-9;]*m|\x1b\\]8;;[^\x1b]*\x1b\\\\")
+
+// plainWriter drops the color codes, for reports written to a file
+type plainWriter struct {
+	w io.Writer
 }
 
-var invoicesf *bool
-var shuhosf *bool
-var checksf *bool
-var translationsf *bool
+func (p plainWriter) Write(b []byte) (int, error) {
+	if _, err := p.w.Write(ansiEscapeRe.ReplaceAll(b, nil)); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+var invoicesf bool
+var shuhosf bool
+var checksf bool
+var translationsf bool
+var authorsf bool
+var sheetsf bool
+var deterministicf bool
+var tapf bool
+var strictf bool
+var maxWarningsf = -1
+var strictParsef bool
+var carryDatesf bool
+var dateTolerancef = -1
+var outputf string
+var copyf bool
+var openf bool
+var rawAmountsf bool
+var precisionf int
+var noGroupingf bool
+
+// report output, tests swap it for a buffer
+var out io.Writer = os.Stdout
+
+// now is fixed by --deterministic so "this year or last year" doesn't drift
+var now = time.Now
+
+// the "now" used for --deterministic runs and golden-file tests
+var deterministicNow = time.Date(2023, time.July, 5, 0, 0, 0, 0, time.UTC)
 
 // entry signatures are Date, Casenum, Type, Wordcount
 type Entry interface {
@@ -49,6 +90,21 @@ type Entry interface {
 	Date() time.Time
 	Rate() string
 	WordCount() string
+	CaseNum() string
+	Location() Location
+}
+
+// Location is the workbook cell range an entry was read from. Col is the
+// column of the cell a finding is about, 1 for A, 0 for the row itself.
+type Location struct {
+	File  string
+	Sheet string
+	Row   int
+	Col   int
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s %s", l.File, l.rowCell())
 }
 
 type InvoiceEntry struct {
@@ -58,17 +114,22 @@ type InvoiceEntry struct {
 	IType      string
 	IWordCount string
 	rate       string
+	po         string
+	client     string
+	loc        Location
+	// the columns the row was read with, for the cell of a finding
+	columns *columnMapping
 }
 
 // stuct methods
 
 // signature doesn't include dates
 func (e InvoiceEntry) signature() string {
-	return fmt.Sprintf("%s %s %s", e.ICaseNum, e.IType, e.IWordCount)
+	return fmt.Sprintf("%s %s %s", matchCase(e.ICaseNum), e.IType, e.IWordCount)
 }
 
 func (e InvoiceEntry) String() string {
-	return fmt.Sprintf("%s, %s, %s, %s, %s, %s", e.rowNum, e.ICaseNum, e.IDate, e.IType, e.IWordCount, e.rate)
+	return fmt.Sprintf("%s, %s, %s, %s, %s, %s", e.rowNum, e.ICaseNum, e.IDate, typeLabel(e.IType), e.IWordCount, e.rate)
 }
 
 func (e InvoiceEntry) Rate() string {
@@ -87,6 +148,14 @@ func (e InvoiceEntry) Type() string {
 	return e.IType
 }
 
+func (e InvoiceEntry) CaseNum() string {
+	return e.ICaseNum
+}
+
+func (e InvoiceEntry) Location() Location {
+	return e.loc
+}
+
 type ShuhoEntry struct {
 	SDate       time.Time
 	SCaseNum    string
@@ -94,6 +163,24 @@ type ShuhoEntry struct {
 	SCWordCount string
 	STWordCount string
 	SAuthor     string
+	SPO         string
+	// an x before the date, x6/5, marks a job cancelled after it was logged
+	Cancelled bool
+	loc       Location
+	columns   *columnMapping
+}
+
+// wordCountField is the field of the word count of e, the shuho has a
+// column per type
+func wordCountField(e Entry) string {
+	if _, ok := e.(ShuhoEntry); !ok {
+		return "words"
+	}
+	if e.Type() == "英文チェック" {
+		return "check"
+	}
+
+	return "translation"
 }
 
 func getShuhoEntryWordCount(e ShuhoEntry) string {
@@ -105,9 +192,9 @@ func getShuhoEntryWordCount(e ShuhoEntry) string {
 	case "英文チェック":
 		wordcount = e.SCWordCount
 	default:
-		//should never happen, the excel file restricts to the two above values
-		fmt.Printf("NOTE: %s - %v, %s\n", e.SType, e.SDate, e.SCaseNum)
-		wordcount = "UNKNOWN"
+		//the excel file restricts to the two above values, anything else is
+		//reported by the unknown-type check and left out of matching
+		wordcount = e.STWordCount + e.SCWordCount
 	}
 
 	return wordcount
@@ -117,13 +204,13 @@ func getShuhoEntryWordCount(e ShuhoEntry) string {
 func (e ShuhoEntry) signature() string {
 	wordcount := getShuhoEntryWordCount(e)
 
-	return fmt.Sprintf("%s %s %s", e.SCaseNum, e.SType, wordcount)
+	return fmt.Sprintf("%s %s %s", matchCase(e.SCaseNum), e.SType, wordcount)
 }
 
 func (e ShuhoEntry) String() string {
 	wordcount := getShuhoEntryWordCount(e)
 
-	return fmt.Sprintf("%v, %s, %s, %s, %s", e.SDate, e.SCaseNum, e.SType, wordcount, e.SAuthor)
+	return fmt.Sprintf("%v, %s, %s, %s, %s", e.SDate, e.SCaseNum, typeLabel(e.SType), wordcount, e.SAuthor)
 }
 
 func (e ShuhoEntry) Date() time.Time {
@@ -142,149 +229,669 @@ func (e ShuhoEntry) Type() string {
 	return e.SType
 }
 
-// print error for structs satisfying Entry interface
-func printEntryError(e Entry) {
-	fmt.Printf("Error: %s\n", e.String())
-	os.Exit(1)
+func (e ShuhoEntry) CaseNum() string {
+	return e.SCaseNum
+}
+
+func (e ShuhoEntry) Location() Location {
+	return e.loc
+}
+
+// error for structs satisfying Entry interface
+func entryError(e Entry, reason string) error {
+	return &ParseError{e.Location(), fmt.Errorf("%s: %s", reason, e.String())}
 }
 
 func greeting() {
-	fmt.Println("------------------------")
-	fmt.Println("Verify Shuho and Invoice")
-	fmt.Println("------------------------")
+	printer.Fprintf(out, "------------------------\n")
+	printer.Fprintf(out, "Verify Shuho and Invoice\n")
+	printer.Fprintf(out, "------------------------\n")
 }
 
+// wasmMain replaces main in the WebAssembly build, see wasm.go
+var wasmMain func()
+
 func main() {
-	invoicesf = flag.Bool("invoices", false, "display every invoice entry")
-	shuhosf = flag.Bool("shuhos", false, "display every shuho entry")
-	checksf = flag.Bool("checks", false, "display all checks")
-	translationsf = flag.Bool("translations", false, "display all translations")
+	if wasmMain != nil {
+		wasmMain()
+		return
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "normalize":
+			normalizeCommand(os.Args[2:])
+			return
+		case "anonymize":
+			anonymizeCommand(os.Args[2:])
+			return
+		case "gen-sample":
+			os.Exit(genSampleCommand(os.Args[2:]))
+		case "dashboard":
+			dashboardCommand(os.Args[2:])
+			return
+		case "export":
+			exportCommand(os.Args[2:])
+			return
+		case "stats":
+			os.Exit(statsCommand(os.Args[2:]))
+		case "daemon":
+			os.Exit(daemonCommand(os.Args[2:]))
+		case "ask":
+			os.Exit(askCommand(os.Args[2:]))
+		case "undo":
+			os.Exit(undoCommand(os.Args[2:]))
+		case "audit-unbilled":
+			os.Exit(auditUnbilledCommand(os.Args[2:]))
+		case "new-sheet":
+			os.Exit(newSheetCommand(os.Args[2:]))
+		case "report":
+			os.Exit(quarterReportCommand(os.Args[2:]))
+		case "tax-export":
+			os.Exit(taxExportCommand(os.Args[2:]))
+		case "reconcile":
+			os.Exit(reconcileCommand(os.Args[2:]))
+		case "version":
+			fmt.Fprintln(out, versionString())
+			return
+		}
+	}
+
+	os.Exit(verify())
+}
+
+// checkFlags registers the flags deciding how the workbooks are parsed and
+// checked, shared by the verification, stats and the daemon
+func checkFlags(fs *flag.FlagSet) {
+	registered := make(map[string]bool)
+	fs.VisitAll(func(f *flag.Flag) { registered[f.Name] = true })
+	defer func() {
+		checkFlagSet, checkFlagNames = fs, nil
+		fs.VisitAll(func(f *flag.Flag) {
+			if !registered[f.Name] {
+				checkFlagNames = append(checkFlagNames, f.Name)
+			}
+		})
+	}()
 
+	fs.Var(&onlySheetsf, "only-sheets", "only parse shuho sheets matching these globs or /regexps/, comma separated")
+	fs.Var(&skipSheetsf, "skip-sheets", "don't parse shuho sheets matching these globs or /regexps/, comma separated")
+	fs.BoolVar(&allSheetsf, "all-sheets", false, "parse shuho sheets named after months outside the invoiced period too")
+	fs.Var(invoiceColumnsf, "invoice-columns", "invoice columns of the fields no, case, type, date, words, rate, po and client, e.g. date=D,rate=F,client=H (taken from the header row of a known layout when not given)")
+	fs.Var(shuhoColumnsf, "shuho-columns", "shuho columns of the fields date, case, type, check, translation, note, author and po, e.g. author=H,po=I")
+	fs.BoolVar(&strictf, "strict", false, "fail the run on any warning")
+	fs.IntVar(&maxWarningsf, "max-warnings", -1, "fail the run when there are more than N warnings (-1 for no limit)")
+	fs.StringVar(&configf, "config", "", "config file (default verifyshuho/config.json in the user config directory)")
+	fs.StringVar(&historyf, "history", "", "append every run to this JSON lines `file` for comparing months")
+	fs.StringVar(&ignoref, "ignore", "", "file of accepted discrepancies: <check id> <case number> <month> per line")
+	fs.StringVar(&timeTrackingf, "time-tracking", "", "Toggl or Clockify CSV `file` of the tracked time, for the tracked-time check")
+	fs.BoolVar(&strictParsef, "strict-parse", false, "report every row skipped while parsing as an error")
+	fs.BoolVar(&aggregatef, "aggregate", false, "compare the words summed per case and type instead of row by row")
+	fs.BoolVar(&fiscalYearf, "fiscal-year", false, "count the year to date totals from April to March")
+	fs.IntVar(&dateTolerancef, "date-tolerance", -1, "match entries by date too, the shuho may log them up to N days before the invoice (-1 ignores dates)")
+	fs.IntVar(&overdueDaysf, "overdue-days", 0, "warn about shuho entries older than N days on no invoice of the history")
+	fs.IntVar(&overdueLookbackf, "overdue-lookback", 90, "how many days back --overdue-days looks for uninvoiced entries")
+	fs.BoolVar(&carryDatesf, "carry-dates", false, "use the last date above for shuho rows with an empty date cell")
+	fs.StringVar(&holidaysf, "holidays", "", "`file` of days off besides the weekends and public holidays, YYYY-MM-DD or YYYY-MM-DD..YYYY-MM-DD per line")
+	fs.BoolVar(&deterministicf, "deterministic", false, "fix the current date for reproducible output")
+}
+
+// reportFlags registers the flags of the report and what a verification
+// writes besides it
+func reportFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&invoicesf, "invoices", false, "display every invoice entry")
+	fs.BoolVar(&shuhosf, "shuhos", false, "display every shuho entry")
+	fs.BoolVar(&checksf, "checks", false, "display all checks")
+	fs.BoolVar(&translationsf, "translations", false, "display all translations")
+	fs.BoolVar(&authorsf, "authors", false, "display word and entry counts per shuho author")
+	fs.BoolVar(&sheetsf, "sheets", false, "display word and entry subtotals per shuho sheet")
+	fs.BoolVar(&daysf, "days", false, "display word and entry subtotals per day of the shuho")
+	fs.Var(&chartf, "chart", "chart the invoiced words per day or week")
+	fs.Var(&reportsf, "report", "write check results as format[:path] (junit, tap, github, html), e.g. junit:results.xml")
+	fs.BoolVar(&tapf, "tap", false, "print check results as TAP on stdout, the normal report goes to stderr")
+	fs.BoolVar(&changedOnlyf, "changed-only", false, "skip the verification when both workbooks are unchanged since a successful run")
+	fs.StringVar(&statef, "state", "", "`file` of the successful runs for --changed-only (default verifyshuho/state.json in the user cache directory)")
+	fs.StringVar(&auditLogf, "audit-log", "", "append a hash chained record of every run with the input checksums and findings to this JSON lines `file`")
+	fs.StringVar(&outputf, "o", "", "write the report to `file` without colors or the greeting")
+	fs.StringVar(&outputf, "output", "", "same as -o")
+	fs.BoolVar(&copyf, "copy", false, "copy the totals to the clipboard")
+	fs.BoolVar(&rawAmountsf, "raw-amounts", false, "print totals as plain numbers instead of yen")
+	fs.IntVar(&precisionf, "precision", 0, "decimals shown in the yen amounts, the totals are worked out exactly either way")
+	fs.BoolVar(&noGroupingf, "no-grouping", false, "print the yen amounts without thousands separators")
+	fs.StringVar(&chartsf, "charts", "", "write charts of the monthly totals and the words per day into `dir`")
+	fs.StringVar(&fixf, "fix", "", "repair a workbook: shuho adds the invoice entries missing from it, invoice corrects rates, word counts and duplicates")
+	fs.StringVar(&fixJournalf, "fix-journal", "", "where applied fixes are recorded for undo (in the user cache directory by default)")
+	fs.BoolVar(&noBackupf, "no-backup", false, "overwrite an existing workbook without a timestamped copy of it")
+	fs.BoolVar(&dryRunf, "dry-run", false, "list the cells --fix would change without writing anything")
+	fs.StringVar(&fixOutputf, "fix-output", "", "where --fix writes the repaired workbook, name.fixed.xlsx next to it by default")
+	fs.StringVar(&hyperlinksf, "hyperlinks", "auto", "link the locations in the report to their cells, auto, always or never")
+	fs.StringVar(&chartFormatf, "chart-format", "svg", "format of the --charts files, svg or png")
+	fs.StringVar(&exportICalf, "export-ical", "", "write the shuho entries of the period as calendar events to this .ics `file`")
+	fs.BoolVar(&openf, "open", false, "open the html report in the default browser")
+	fs.Var(&markersf, "markers", "mark check results with ascii (OKAY... and ERROR:), emoji (✓ and ✗) or plain text")
+	fs.Var(&langf, "lang", "output language, en also translates the work types, ja the messages")
+	fs.BoolVar(&versionf, "version", false, "print the version, commit and build date")
+}
+
+// printUsage lists the options generated from the registered flags between
+// what the workbooks can be and the other commands
+func printUsage() {
+	printer.Fprintf(out, "\033[1;31mERROR Usage:\033[0m ./verifyshuho [OPTIONS] <Shuho.xlsx> <Invoice.xlsx>\n")
+	printer.Fprintf(out, "Workbooks can also be legacy Excel 97-2003 .xls or OpenDocument .ods files, - reads one from stdin\n")
+	printer.Fprintf(out, "A name.schema.json next to a workbook gives the columns, header, date formats and skipped rows of other layouts\n")
+	printer.Fprintf(out, "Either side can be an https:// or s3:// URL, s3 uses the AWS_ credentials and region from the environment\n")
+	printer.Fprintf(out, "SharePoint and OneDrive share links are fetched with GRAPH_ACCESS_TOKEN or the AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET app\n")
+	printer.Fprintf(out, "The invoice can be a .zip of invoices, each is verified against the shuho\n")
+	printer.Fprintf(out, "The invoice can be a directory of the team's invoices, each is verified against the shuho rows of its author\n")
+	printer.Fprintf(out, "Either side can be a .json or .csv of entries written by the export command\n")
+	flag.CommandLine.SetOutput(out)
+	flag.PrintDefaults()
+	fmt.Fprintln(out, "")
+	printer.Fprintf(out, "./verifyshuho normalize <in.xlsx> <out.xlsx> write a cleaned copy of a workbook\n")
+	printer.Fprintf(out, "./verifyshuho anonymize <in.xlsx> <out.xlsx> [<in.xlsx> <out.xlsx>...] replace case numbers, authors and rates with synthetic values, matching across the workbooks\n")
+	printer.Fprintf(out, "./verifyshuho gen-sample <shuho.xlsx> <invoice.xlsx> write a matched pair of sample workbooks\n")
+	printer.Fprintf(out, "./verifyshuho export [--format json|csv] <workbook.xlsx> print the parsed entries\n")
+	printer.Fprintf(out, "./verifyshuho stats [--json] <Shuho.xlsx> <Invoice.xlsx> print run metrics instead of the report\n")
+	printer.Fprintf(out, "./verifyshuho dashboard --history history.jsonl serve a page of the recorded months on localhost\n")
+	printer.Fprintf(out, "./verifyshuho report --quarter 2024Q2 [--fiscal-year] --history history.jsonl sum up a quarter and compare it with the one before\n")
+	printer.Fprintf(out, "./verifyshuho tax-export --year 2024 [--fiscal-year] --history history.jsonl CSV of the monthly income and withholding for tax-filing software\n")
+	printer.Fprintf(out, "./verifyshuho reconcile [--payer name] --history history.jsonl <statement.csv> match the months to the payments of a bank statement\n")
+	printer.Fprintf(out, "./verifyshuho daemon [--socket path] answer verifications on a unix socket, keeping the workbooks in memory\n")
+	printer.Fprintf(out, "./verifyshuho ask verify <Shuho.xlsx> <Invoice.xlsx> | ask case <Shuho.xlsx> <case number> query the daemon\n")
+	printer.Fprintf(out, "./verifyshuho new-sheet [--month YYYY-MM] [--holidays days-off.txt] <shuho.xlsx> add next month's sheet with its business days from the template sheet\n")
+	printer.Fprintf(out, "./verifyshuho audit-unbilled <shuho.xlsx> <invoice.xlsx>... list the entries of every shuho sheet on none of the invoices\n")
+	printer.Fprintf(out, "./verifyshuho undo [--force] restore the workbook the last --fix wrote\n")
+	printer.Fprintf(out, "./verifyshuho version print the version, commit and build date\n")
+}
+
+// loadSettings reads the config, history, ignore and time tracking files the
+// flags name, once before the verifications of a run
+func loadSettings() error {
+	var err error
+	config, err = loadConfig(configf)
+	if err == nil {
+		err = registerRules(config.Rules)
+	}
+	if err == nil {
+		err = setRowPatterns(config.Patterns)
+	}
+	if len(config.Rates) > 0 {
+		invoiceRates = config.Rates
+	}
+	if err == nil {
+		err = setRatePeriods(config.RatePeriods)
+	}
+	if err == nil {
+		err = setRateTiers(config.RateTiers)
+	}
+	if err != nil {
+		return err
+	}
+
+	if historyf == "" {
+		historyf = config.HistoryFile
+	}
+	if historyf != "" {
+		history, err = loadHistory(historyf)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ignoref == "" {
+		ignoref = config.IgnoreFile
+	}
+	if ignoref != "" {
+		ignoreRules, err = loadIgnoreFile(ignoref)
+		if err != nil {
+			return err
+		}
+	}
+
+	if timeTrackingf != "" {
+		trackedHours, err = loadTimeTracking(timeTrackingf)
+		if err != nil {
+			return err
+		}
+	}
+
+	if holidaysf == "" {
+		holidaysf = config.HolidaysFile
+	}
+	if holidaysf != "" {
+		daysOff, err = loadHolidayFile(holidaysf)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verify compares the shuho and invoice given on the command line, the
+// returned exit status is non-zero when the verification failed
+func verify() int {
+	checkFlags(flag.CommandLine)
+	reportFlags(flag.CommandLine)
+	flag.Usage = printUsage
 	flag.Parse()
 
+	if versionf {
+		fmt.Fprintln(out, versionString())
+		return 0
+	}
+
+	if deterministicf {
+		now = func() time.Time { return deterministicNow }
+	}
+
+	if err := loadSettings(); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	if hyperlinksf != "auto" && hyperlinksf != "always" && hyperlinksf != "never" {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m Unknown hyperlinks mode %q, use auto, always or never\n", hyperlinksf)
+		return 2
+	}
+	hyperlinks = useHyperlinks(hyperlinksf)
+	if fixf != "" && fixf != "shuho" && fixf != "invoice" {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m Unknown --fix %q, use shuho or invoice\n", fixf)
+		return 2
+	}
+	if dryRunf && fixf == "" {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m --dry-run previews --fix, give both\n")
+		return 2
+	}
+	if precisionf < 0 || precisionf > 6 {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m --precision %d, use 0 to 6 decimals\n", precisionf)
+		return 2
+	}
+	if chartFormatf != "svg" && chartFormatf != "png" {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m Unknown chart format %q, use svg or png\n", chartFormatf)
+		return 2
+	}
+
+	//keep stdout pure TAP for prove
+	if tapf {
+		reportsf = append(reportsf, "tap")
+		out = os.Stderr
+	}
+
 	if flag.NArg() != 2 {
-		fmt.Println("\033[1;31mERROR Usage:\033[0m ./verifyshuho [OPTIONS] <Shuho.xlsx> <Invoice.xlsx>")
-		fmt.Println("--invoices show all invoice entries")
-		fmt.Println("--shuhos show all shuho entries")
-		fmt.Println("--translations show all translations")
-		fmt.Println("--checks show all checks")
-		return
+		printUsage()
+		return 2
 	}
 
 	shuhoFileName := flag.Arg(0)
 	invoiceFileName := flag.Arg(1)
+	if shuhoFileName == "-" && invoiceFileName == "-" {
+		printer.Fprintf(out, "\033[1;31mERROR:\033[0m Only one workbook can be read from stdin\n")
+		return 2
+	}
 
-	var shuhoEntries []Entry
-	var invoiceEntries []Entry
+	if outputf != "" {
+		f, err := os.Create(outputf)
+		if err != nil {
+			fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+			return 2
+		}
+		defer f.Close()
+		out = plainWriter{f}
+	} else {
+		greeting()
+	}
+	//plain markers are for terminals and logs that show the color codes as text
+	if markersf == "plain" {
+		out = plainWriter{out}
+	}
 
-	greeting()
+	if err := loadSchemas(shuhoFileName, invoiceFileName); err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
 
-	fshuho, err := excelize.OpenFile(shuhoFileName)
+	fshuho, err := openWorkbook(shuhoFileName)
 	if err != nil {
-		fmt.Println(err)
-		return
+		fmt.Fprintln(out, err)
+		return 2
+	}
+	defer func() {
+		// Close the shuho spreadsheet.
+		if err := fshuho.Close(); err != nil {
+			fmt.Fprintln(out, err)
+		}
+	}()
+	shuhoInput, err := newInputFile(shuhoFileName)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	//the agency sends the invoices of several months in one zip
+	if strings.EqualFold(filepath.Ext(invoiceFileName), ".zip") {
+		return verifyArchive(fshuho, shuhoInput, invoiceFileName)
 	}
-	finvoice, err := excelize.OpenFile(invoiceFileName)
+	//a team lead verifies the invoices of every translator against the shared shuho
+	if info, err := os.Stat(invoiceFileName); err == nil && info.IsDir() {
+		return verifyTeam(fshuho, shuhoInput, invoiceFileName)
+	}
+
+	finvoice, err := openWorkbook(invoiceFileName)
 	if err != nil {
-		fmt.Println(err)
-		return
+		fmt.Fprintln(out, err)
+		return 2
 	}
 	defer func() {
 		// Close the invoice spreadsheet.
 		if err := finvoice.Close(); err != nil {
-			fmt.Println(err)
-		}
-		// Close the shuho spreadsheet.
-		if err := fshuho.Close(); err != nil {
-			fmt.Println(err)
+			fmt.Fprintln(out, err)
 		}
 	}()
+	invoiceInput, err := newInputFile(invoiceFileName)
+	if err != nil {
+		fmt.Fprintln(out, "\033[1;31mERROR:\033[0m", err)
+		return 2
+	}
+
+	return verifyWorkbooks(fshuho, finvoice, shuhoInput, invoiceInput)
+}
 
+// verifyData verifies workbooks given as their contents, for callers without
+// files such as the browser. The report is returned as plain text.
+func verifyData(shuhoName string, shuhoData []byte, invoiceName string, invoiceData []byte) (string, int) {
+	fshuho, err := dataWorkbook(shuhoName, shuhoData)
 	if err != nil {
-		fmt.Println("ERROR:", err)
-		return
+		return fmt.Sprintln("ERROR:", err), 2
+	}
+	defer fshuho.Close()
+	finvoice, err := dataWorkbook(invoiceName, invoiceData)
+	if err != nil {
+		return fmt.Sprintln("ERROR:", err), 2
 	}
+	defer finvoice.Close()
 
-	invoiceEntries = parseInvoice(finvoice)
-	shuhoEntries = parseShuho(fshuho)
+	return verifyToText(fshuho, finvoice, inputFile{shuhoName, fmt.Sprintf("%x", sha256.Sum256(shuhoData))}, inputFile{invoiceName, fmt.Sprintf("%x", sha256.Sum256(invoiceData))})
+}
 
-	if shuhoEntries == nil || invoiceEntries == nil {
-		fmt.Println("Empty Shuho or Invoice Entries variable")
-		return
+// verifyWorkbooks parses and verifies an opened shuho and invoice, and
+// records the run in the files the flags ask for
+func verifyWorkbooks(fshuho, finvoice Workbook, shuhoInput, invoiceInput inputFile) int {
+	status, _, _ := verifyAuthored(fshuho, finvoice, shuhoInput, invoiceInput, false)
+	return status
+}
+
+// verifyAuthored is verifyWorkbooks checking the invoice against the shuho
+// rows of its author only when byAuthor is set. It returns the author too,
+// and the shuho entries of the invoiced period before they were narrowed.
+func verifyAuthored(fshuho, finvoice Workbook, shuhoInput, invoiceInput inputFile, byAuthor bool) (int, string, []Entry) {
+	var err error
+	inputFiles = []inputFile{shuhoInput, invoiceInput}
+	parseIssues, cancelledEntries = nil, nil
+	shuhoPeriod.start, shuhoPeriod.end = time.Time{}, time.Time{}
+	stats = runStats{Findings: make(map[string]int)}
+
+	//--changed-only skips workbooks that passed before, the stdin workbook was read above so it has a checksum too
+	if statef == "" && changedOnlyf {
+		statef = defaultStatePath()
+	}
+	var state runState
+	if statef != "" {
+		state, err = loadState(statef)
+		if err != nil {
+			printer.Fprintf(out, "\033[1;31mERROR:\033[0m Reading the state file: %s\n", err)
+		}
+	}
+	if cached, ok := state.Runs[stateKey(inputFiles[0], inputFiles[1])]; ok && changedOnlyf {
+		printer.Fprintf(out, "Unchanged since the successful run at %s, pre-tax total %s\n", cached.Time, formatYen(cached.Pretax))
+		return 0, "", nil
+	}
+
+	shuhoEntries, invoiceEntries, parseErr := parseWorkbooks(fshuho, finvoice)
+	periodEntries, author := shuhoEntries, ""
+	if byAuthor {
+		author = invoiceAuthor(finvoice.Name(), parsedInvoiceHeader, shuhoEntries)
+		if author == "" {
+			printer.Fprintf(out, "\033[1;31mERROR:\033[0m None of the shuho authors is the translator of %s\n", finvoice.Name())
+			return 2, "", periodEntries
+		}
+		shuhoEntries, cancelledEntries = authoredBy(shuhoEntries, author), authoredBy(cancelledEntries, author)
+		stats.ShuhoEntries = len(shuhoEntries)
 	}
 
-	fmt.Printf("Invoice Entries: %d\n", len(invoiceEntries))
-	fmt.Printf("Shuho Entries: %d\n", len(shuhoEntries))
-	fmt.Println("")
-	fmt.Printf("Total Translations: \033[1;36m%d\033[0m\n", sumOfTranslations(invoiceEntries))
-	fmt.Printf("Total Checks: %d\n", sumOfChecks(invoiceEntries))
+	if len(shuhoEntries) == 0 || len(invoiceEntries) == 0 {
+		printer.Fprintf(out, "Empty Shuho or Invoice Entries variable\n")
+		printParseIssues(parseErr, parseIssues)
+		return 2, author, periodEntries
+	}
+
+	stats.Cancelled = sumCancelled(getScopedShuho(cancelledEntries, invoiceEntries))
 
-	fmt.Println("")
+	results := report(shuhoEntries, invoiceEntries)
 
-	ensureRatesAreCorrect(invoiceEntries)
-	ensureNoDuplicateInvoiceEntries(invoiceEntries)
-	ensureInvoiceEntriesAreInShuho(shuhoEntries, invoiceEntries)
-	ensureShuhoEntriesAreInInvoice(shuhoEntries, invoiceEntries)
+	//every row that failed to parse or was skipped, after the report instead of stopping at the first
+	printParseIssues(parseErr, parseIssues)
+	status := 1
+	if parseErr != nil {
+		printer.Fprintf(out, "\n\033[1;31mFAILED:\033[0m rows could not be parsed\n")
+	} else {
+		status = exitStatus(results)
+	}
 
-	p := message.NewPrinter(language.English)
+	switch fixf {
+	case "shuho":
+		if err := fixShuho(fshuho, missingFromShuho(results)); err != nil {
+			printer.Fprintf(out, "\033[1;31mERROR:\033[0m Fixing the shuho: %s\n", err)
+		}
+	case "invoice":
+		if err := fixInvoice(finvoice, shuhoEntries, invoiceEntries, results); err != nil {
+			printer.Fprintf(out, "\033[1;31mERROR:\033[0m Fixing the invoice: %s\n", err)
+		}
+	}
 
-	fmt.Println("")
-	ieTotal := roundFloat(sumEntries(invoiceEntries, "翻訳"), 2)
-	p.Printf("Total for translations: \t%.2f\n", ieTotal)
-	icTotal := roundFloat(sumEntries(invoiceEntries, "英文チェック"), 2)
-	p.Printf("Total for Checks:     \t\t%.2f\n", icTotal)
-	pretax := icTotal + ieTotal + 81.16
-	p.Printf("\033[1;31mPre-T Total: \t\t\t%.2f\033[0m (%.2f /YR)\n", pretax, pretax*12)
-	//p.Printf("\033[1;32mAfter-T Total:          \t\t%.0f\033[0m\n", roundFloat((pretax*0.8979)-330, 2))
+	record := newHistoryRecord(fshuho.Name(), finvoice.Name(), author, invoiceEntries, status == 0)
+	if historyf != "" {
+		if err := appendHistory(historyf, record); err != nil {
+			printer.Fprintf(out, "\033[1;31mERROR:\033[0m Recording the run: %s\n", err)
+		}
+	}
+	if statef != "" && status == 0 {
+		state.Runs[stateKey(inputFiles[0], inputFiles[1])] = stateEntry{now().Format("2006-01-02 15:04"), inputFiles[0], inputFiles[1], stats.Pretax}
+		if err := saveState(statef, state); err != nil {
+			printer.Fprintf(out, "\033[1;31mERROR:\033[0m Writing the state file: %s\n", err)
+		}
+	}
+	if auditLogf != "" {
+		audit := newAuditRecord(inputFiles[0], inputFiles[1], invoiceEntries, results, status)
+		if err := appendAuditRecord(auditLogf, audit); err != nil {
+			printer.Fprintf(out, "\033[1;31mERROR:\033[0m Writing the audit log: %s\n", err)
+		}
+	}
+	if chartsf != "" {
+		if err := writeCharts(chartsf, chartFormatf, historyCharts(append(history, record))); err != nil {
+			printer.Fprintf(out, "\033[1;31mERROR:\033[0m Writing the charts: %s\n", err)
+		} else {
+			printer.Fprintf(out, "Wrote the charts to %s\n", chartsf)
+		}
+	}
+	if exportICalf != "" {
+		if err := writeICalFile(exportICalf, getScopedShuho(shuhoEntries, invoiceEntries)); err != nil {
+			printer.Fprintf(out, "\033[1;31mERROR:\033[0m Writing the calendar: %s\n", err)
+		} else {
+			printer.Fprintf(out, "Wrote the calendar to %s\n", exportICalf)
+		}
+	}
 
-	if *invoicesf {
+	return status, author, periodEntries
+}
+
+// parseWorkbooks parses the invoice and then the shuho sheets of its period,
+// and records the counts and the time it took in stats
+func parseWorkbooks(fshuho, finvoice Workbook) ([]Entry, []Entry, error) {
+	parseStart := time.Now()
+	invoiceEntries, invoiceErr := parseInvoice(finvoice)
+	if len(invoiceEntries) > 0 {
+		shuhoPeriod.start, shuhoPeriod.end = invoiceEntries[0].Date(), invoiceEntries[len(invoiceEntries)-1].Date()
+	}
+	shuhoEntries, shuhoErr := parseShuho(fshuho)
+	parseErr := errors.Join(shuhoErr, invoiceErr)
+	stats.Shuho, stats.Invoice = fshuho.Name(), finvoice.Name()
+	stats.ShuhoEntries, stats.InvoiceEntries = len(shuhoEntries), len(invoiceEntries)
+	stats.RowsSkipped, stats.ParseErrors = len(parseIssues), countErrors(parseErr)
+	stats.ParseSeconds = time.Since(parseStart).Seconds()
+	_, _, stats.Pretax = invoiceTotals(invoiceEntries)
+
+	return shuhoEntries, invoiceEntries, parseErr
+}
+
+// report runs every check and prints the summary for the parsed entries
+func report(shuhoEntries []Entry, invoiceEntries []Entry) []CheckResult {
+	printer.Fprintf(out, "Invoice Entries: %d\n", len(invoiceEntries))
+	printer.Fprintf(out, "Shuho Entries: %d\n", len(shuhoEntries))
+	for _, input := range inputFiles {
+		printer.Fprintf(out, "SHA-256 %s: %s\n", input.Path, input.SHA256)
+	}
+	fmt.Fprintln(out, "")
+	printer.Fprintf(out, "Total Translations: \033[1;36m%d\033[0m\n", sumOfTranslations(invoiceEntries))
+	printer.Fprintf(out, "Total Checks: %d\n", sumOfChecks(invoiceEntries))
+	if unknown := sumOfUnknownTypes(invoiceEntries) + sumOfUnknownTypes(getScopedShuho(shuhoEntries, invoiceEntries)); unknown > 0 {
+		printer.Fprintf(out, "Unknown Types: \033[1;33m%d\033[0m\n", unknown)
+	}
+	if cancelled := getScopedShuho(cancelledEntries, invoiceEntries); len(cancelled) > 0 {
+		printer.Fprintf(out, "Cancelled Entries: %d\n", len(cancelled))
+	}
+
+	fmt.Fprintln(out, "")
+
+	results := runChecks(shuhoEntries, invoiceEntries)
+	if stats.Ignored > 0 {
+		printer.Fprintf(out, "Ignored %d known exceptions\n", stats.Ignored)
+	}
+	printCheckResults(results)
+	writeReports(results)
+
+	//--copy puts the totals on the clipboard as well
+	var summary bytes.Buffer
+	totals := out
+	if copyf {
+		totals = io.MultiWriter(out, plainWriter{&summary})
+	}
+
+	fmt.Fprintln(out, "")
+	ieTotal, icTotal, pretax := invoiceTotals(invoiceEntries)
+	printer.Fprintf(totals, "Total for translations: \t%s\n", formatYen(ieTotal))
+	printer.Fprintf(totals, "Total for Checks:     \t\t%s\n", formatYen(icTotal))
+	if yearly, ok := annualProjection(invoiceEntries[0].Date().Format("2006-01"), pretax); ok {
+		printer.Fprintf(totals, "\033[1;31mPre-T Total: \t\t\t%s\033[0m (%s /YR)\n", formatYen(pretax), formatYen(yearly))
+	} else {
+		printer.Fprintf(totals, "\033[1;31mPre-T Total: \t\t\t%s\033[0m\n", formatYen(pretax))
+	}
+
+	fmt.Fprintln(out, "")
+	month := invoiceEntries[0].Date().Format("2006-01")
+	printTypeShares(printer.Sprintf("Shares this month:"), invoiceEntries)
+	if ytd := yearToDateEntries(month, invoiceEntries); len(ytd) > len(invoiceEntries) {
+		title := printer.Sprintf("Shares in %d so far:", yearOf(month))
+		if fiscalYearf {
+			title = printer.Sprintf("Shares in FY%d so far:", yearOf(month))
+		}
+		printTypeShares(title, ytd)
+	}
+	printWordStats(invoiceEntries)
+	//p.Fprintf(out, "\033[1;32mAfter-T Total:          \t\t%.0f\033[0m\n", roundFloat((pretax*0.8979)-330, 2))
+
+	if copyf {
+		if err := copyToClipboard(summary.String()); err != nil {
+			printer.Fprintf(out, "\033[1;31mERROR:\033[0m Copying the totals: %s\n", err)
+		} else {
+			showCheckSuccess(translate("Copied the totals to the clipboard"))
+		}
+	}
+
+	if cancelled := getScopedShuho(cancelledEntries, invoiceEntries); len(cancelled) > 0 {
+		printCancelled(cancelled)
+	}
+
+	if invoicesf {
 		printAllInvoices(invoiceEntries)
 	}
 
-	if *shuhosf {
+	if shuhosf {
 		printAllShuhos(getScopedShuho(shuhoEntries, invoiceEntries))
 	}
 
-	if *translationsf {
+	if translationsf {
 		printAllTranslations(getScopedShuho(shuhoEntries, invoiceEntries))
 	}
 
-	if *checksf {
+	if checksf {
 		printAllChecks(getScopedShuho(shuhoEntries, invoiceEntries))
 	}
 
-	//main
+	if authorsf {
+		printAuthorBreakdown(shuhoEntries, invoiceEntries)
+	}
+
+	if sheetsf {
+		printSheetSubtotals(shuhoEntries, invoiceEntries)
+	}
+
+	if daysf {
+		printDailyThroughput(shuhoEntries, invoiceEntries)
+	}
+
+	if chartf != "" {
+		printWorkloadChart(invoiceEntries, chartf)
+	}
+
+	return results
 }
 
 func printAllChecks(entries []Entry) {
-	colorize(ColorGreen, "\n** All Checks: ")
+	colorize(ColorGreen, translate("\n** All Checks: "))
 	for index, entry := range entries {
 		if entry.Type() == "英文チェック" {
-			fmt.Printf("%d: %s\n", index, entry.String())
+			fmt.Fprintf(out, "%d: %s\n", index, entry.String())
 		}
 	}
 }
 
 func printAllTranslations(entries []Entry) {
-	colorize(ColorGreen, "\n** All Translations: ")
+	colorize(ColorGreen, translate("\n** All Translations: "))
 	for index, entry := range entries {
 		if entry.Type() == "翻訳" {
-			fmt.Printf("%d: %s\n", index, entry.String())
+			fmt.Fprintf(out, "%d: %s\n", index, entry.String())
 		}
 	}
 }
 
+// printAllInvoices lists the invoice entries with what each bills and the
+// running subtotal, to find where a total differs from the agency's
 func printAllInvoices(entries []Entry) {
-	colorize(ColorGreen, "\n** All Invoices: ")
+	colorize(ColorGreen, translate("\n** All Invoices: "))
+	var subtotal float64
 	for index, entry := range entries {
-		fmt.Printf("%d: %s\n", index, entry.String())
+		amount := lineAmount(entry)
+		subtotal += amount
+		printer.Fprintf(out, "%d: %s → %s, subtotal %s\n", index, entry.String(), formatYen(amount), formatYen(subtotal))
 	}
 }
 
+// printCancelled lists the cancelled shuho entries of the period, they're
+// in no total and shouldn't be on the invoice
+func printCancelled(entries []Entry) {
+	colorize(ColorGreen, translate("\n** Cancelled Entries: "))
+	for index, entry := range entries {
+		fmt.Fprintf(out, "%d: %s\n", index, entry.String())
+	}
+	s := sumCancelled(entries)
+	printer.Fprintf(out, "Cancelled in the period: %d entries, %d translation words and %d check words unpaid\n", s.Entries, s.TranslationWords, s.CheckWords)
+}
+
 func printAllShuhos(entries []Entry) {
-	colorize(ColorGreen, "\n** All Shuhos: ")
+	colorize(ColorGreen, translate("\n** All Shuhos: "))
 	for index, entry := range entries {
-		fmt.Printf("%d: %s\n", index, entry.String())
+		fmt.Fprintf(out, "%d: %s\n", index, entry.String())
 	}
 }
 
@@ -293,21 +900,43 @@ func roundFloat(val float64, precision uint) float64 {
 	return math.Round(val*ratio) / ratio
 }
 
+// invoiceTotals are the translation and check totals and the pre-tax total of the invoice
+func invoiceTotals(invoiceEntries []Entry) (translations, checks, pretax float64) {
+	translations = roundFloat(sumEntries(invoiceEntries, "翻訳"), 2)
+	checks = roundFloat(sumEntries(invoiceEntries, "英文チェック"), 2)
+
+	return translations, checks, checks + translations + 81.16
+}
+
+// formatYen prints amount as yen grouped the way the output language groups
+// numbers, e.g. ¥418,491, to --precision decimals and without the grouping
+// with --no-grouping, or as the plain number with --raw-amounts
+func formatYen(amount float64) string {
+	if rawAmountsf {
+		return strconv.FormatFloat(roundFloat(amount, 2), 'f', -1, 64)
+	}
+
+	//rounded half away from zero first, %f would round half to even
+	amount = roundFloat(amount, uint(precisionf))
+	if noGroupingf {
+		return fmt.Sprintf("¥%.*f", precisionf, amount)
+	}
+
+	return printer.Sprintf("¥%.*f", precisionf, amount)
+}
+
 // sum screening by Type() (translation or check)
 func sumEntries(ientries []Entry, eType string) float64 {
 	var total float64
 
 	for _, ie := range ientries {
 		if ie.Type() == eType {
-			rate, _ := strconv.ParseFloat(ie.Rate(), 64)
-			wordc, err := strconv.ParseFloat(ie.WordCount(), 64)
-			if err != nil {
-				fmt.Println(err)
-			}
-
-			total += wordc * rate
+			total += lineAmount(ie)
 		}
 	}
+	if !config.Rounding.PerLine {
+		total = roundYen(total, config.Rounding.Mode)
+	}
 
 	return total
 }
@@ -316,132 +945,265 @@ func sumEntries(ientries []Entry, eType string) float64 {
 // printAllEntries(shuhoEntries)
 func printAllEntries(entries []Entry) {
 	for index, entry := range entries {
-		fmt.Printf("%d: %s\n", index, entry.String())
+		fmt.Fprintf(out, "%d: %s\n", index, entry.String())
 	}
 }
 
-func getDate(txtDate string) time.Time {
+func getDate(txtDate string) (time.Time, error) {
+	if date, ok := parseSchemaDate(txtDate); ok {
+		return date, nil
+	}
 	entryDate, err := time.Parse("01-02-06", txtDate)
 
 	if err != nil {
 		entryDate, err = time.Parse("1/2", txtDate)
 		if err != nil {
-			fmt.Printf("ERROR: Invalid Date %s", txtDate)
-			os.Exit(2)
+			//other date styles accepted by the configured patterns
+			if date := normalizeInvoiceDate(txtDate); date != "" {
+				return time.Parse("01-02-06", date)
+			}
+			if date := normalizeShuhoDate(normalizeText(txtDate)); date != "" {
+				return getDate(date)
+			}
+			return entryDate, fmt.Errorf("invalid date %s", txtDate)
 		}
 		entryDate = thisYearOrLastYear(entryDate)
 	}
 
-	return entryDate
+	return entryDate, nil
 }
 
 func thisYearOrLastYear(theDate time.Time) time.Time {
 	var MyYear int
 
 	//if the month/day is earlier than a week from now, assume it's last year
-	if theDate.YearDay() <= time.Now().AddDate(0, 0, 7).YearDay() {
-		MyYear = time.Now().Year()
+	if theDate.YearDay() <= now().AddDate(0, 0, 7).YearDay() {
+		MyYear = now().Year()
 	} else {
-		MyYear = time.Now().AddDate(0, 0, 7).Year() - 1 //take the year -1 (use date after add date in case it's late Dec)
+		MyYear = now().AddDate(0, 0, 7).Year() - 1 //take the year -1 (use date after add date in case it's late Dec)
 	}
 
 	return time.Date(MyYear, theDate.Month(), theDate.Day(), 0, 0, 0, theDate.Nanosecond(), theDate.Location())
 }
 
-func ensureRatesAreCorrect(entries []Entry) {
-	var entry Entry
-	var errors int
+func ensureRatesAreCorrect(entries []Entry) []Finding {
+	var findings []Finding
 
-	for _, entry = range entries {
-		if entry.Rate() == "18" {
-			if entry.Type() != "翻訳" {
-				errors++
+	for _, entry := range entries {
+		//a small job billed flat has the amount in its rate column
+		if tier, ok := tierFor(entry); ok {
+			if !sameRate(entry.Rate(), tier.Flat) {
+				findings = append(findings, Finding{Message: printer.Sprintf("Rate %s is not the flat %s of %s up to %d words (Row %s)", entry.Rate(), tier.Flat, typeLabel(entry.Type()), tier.MaxWords, entry.String()), Entry: entry, Field: "rate"})
 			}
-		} else if entry.Rate() == "1.4" {
-			if entry.Type() != "英文チェック" {
-				errors++
+			continue
+		}
+		if client, rate, ok := clientRate(entry); ok {
+			if !sameRate(entry.Rate(), rate) {
+				findings = append(findings, Finding{Message: printer.Sprintf("Rate %s is not the rate %s of %s for %s (Row %s)", entry.Rate(), rate, typeLabel(entry.Type()), client, entry.String()), Entry: entry, Field: "rate"})
 			}
+			continue
+		}
+		if (entry.Rate() == "18" && entry.Type() != "翻訳") || (entry.Rate() == "1.4" && entry.Type() != "英文チェック") {
+			findings = append(findings, Finding{Message: printer.Sprintf("Rate is incorrect (Row %s)", entry.String()), Entry: entry, Field: "rate"})
+		} else if p, ok := ratePeriodFor(entry.Type(), entry.Date()); ok && !sameRate(entry.Rate(), p.Rate) {
+			findings = append(findings, Finding{Message: printer.Sprintf("Rate %s is not the rate %s of %s on %s (Row %s)", entry.Rate(), p.Rate, typeLabel(entry.Type()), entry.Date().Format("2006-01-02"), entry.String()), Entry: entry, Field: "rate"})
 		}
 	}
 
-	if errors != 0 {
-		fmt.Printf("\033[1;31mERROR:\033[0m Rate is incorrect (Row %s)\n", entry.String())
-	} else {
-		showCheckSuccess("Invoice rates are correct")
-	}
+	return findings
 }
 
-func ensureNoDuplicateInvoiceEntries(entries []Entry) {
-	var entry Entry
-	var copies int
+// ensureRatesInTable reports rates that are no type's rate at all, a
+// mistyped 180 say, with the rate closest to them
+func ensureRatesInTable(entries []Entry, rates []string) []Finding {
+	var findings []Finding
 
-	for _, entry = range entries {
-		copies = 0
-		for _, nextentry := range entries {
-			if entry.signature() == nextentry.signature() {
-				copies++
+	for _, entry := range entries {
+		//flat amounts are the rates check's
+		if _, ok := tierFor(entry); ok {
+			continue
+		}
+		rate, err := strconv.ParseFloat(entry.Rate(), 64)
+		if err != nil {
+			findings = append(findings, Finding{Message: printer.Sprintf("Rate %q is not a number (Row %s)", entry.Rate(), entry.String()), Entry: entry, Field: "rate"})
+			continue
+		}
+		var nearest string
+		distance := math.Inf(1)
+		for _, valid := range rates {
+			v, _ := strconv.ParseFloat(valid, 64)
+			if d := math.Abs(v - rate); d < distance || d == distance && valid < nearest {
+				nearest, distance = valid, d
 			}
 		}
+		if distance > 0 {
+			findings = append(findings, Finding{Message: printer.Sprintf("Rate %s is not in the rate table, nearest is %s (Row %s)", entry.Rate(), nearest, entry.String()), Entry: entry, Field: "rate"})
+		}
 	}
 
-	if copies != 1 {
-		fmt.Printf("\033[1;31mERROR:\033[0m Duplicate entry (Row %s)\n", entry.String())
-	} else {
-		showCheckSuccess("No Duplicate Invoice Entries")
+	return findings
+}
+
+func ensureNoDuplicateInvoiceEntries(entries []Entry) []Finding {
+	var findings []Finding
+	entries = matchable(entries)
+	seen := make(map[string]bool)
+
+	for _, entry := range entries {
+		if seen[entry.signature()] {
+			findings = append(findings, Finding{Message: printer.Sprintf("Duplicate entry (Row %s)", entry.String()), Entry: entry})
+		}
+		seen[entry.signature()] = true
 	}
+
+	return findings
 }
 
-func ensureInvoiceEntriesAreInShuho(sentries []Entry, ientries []Entry) {
-	scopedShuhoEntries := getScopedShuho(sentries, ientries)
-	var totalerrors, copies int
+func ensureInvoiceEntriesAreInShuho(sentries []Entry, ientries []Entry) []Finding {
+	var findings []Finding
+	scopedShuhoEntries := matchable(getScopedShuho(sentries, ientries))
+	var copies int
+
+	split := make(map[Location][]Entry)
+	for _, s := range findSplitDeliveries(scopedShuhoEntries, matchable(ientries)) {
+		split[s.invoice.Location()] = s.parts
+	}
+
+	for _, ientry := range matchable(ientries) {
+		if parts, ok := split[ientry.Location()]; ok {
+			findings = append(findings, Finding{Message: printer.Sprintf("Split delivery of %d Shuho Entries (%s): Row %s", len(parts), describeParts(parts), ientry.String()), Entry: ientry, Severity: SeverityInfo})
+			continue
+		}
 
-	for _, ientry := range ientries {
 		copies = 0
 		for _, sentry := range scopedShuhoEntries {
-			if sentry.signature() == ientry.signature() {
+			if sentry.signature() == ientry.signature() && datesMatch(ientry, sentry) {
 				copies++
 			}
 		}
 
-		if copies < 1 {
-			fmt.Printf("\033[1;31mERROR:\033[0m Invoice Entry Not in Shuho: Row %s\n", ientry.String())
-			totalerrors++
+		if copies >= 1 {
+			continue
+		}
+
+		if diff, ok := nearWordCountMatch(ientry, scopedShuhoEntries); ok {
+			findings = append(findings, Finding{Message: printer.Sprintf("Word count off by %d from the Shuho: Row %s", diff, ientry.String()), Entry: ientry, Severity: SeverityWarning})
+		} else {
+			findings = append(findings, Finding{Message: printer.Sprintf("Invoice Entry Not in Shuho: Row %s", ientry.String()), Entry: ientry})
 		}
 	}
 
-	if totalerrors == 0 {
-		showCheckSuccess("All Invoice Entries are in the Shuho")
-	}
+	return findings
 }
 
-func ensureShuhoEntriesAreInInvoice(sentries []Entry, ientries []Entry) {
-	scopedShuhoEntries := getScopedShuho(sentries, ientries)
-	var totalerrors, copies int
+func ensureShuhoEntriesAreInInvoice(sentries []Entry, ientries []Entry) []Finding {
+	var findings []Finding
+	scopedShuhoEntries := matchable(getScopedShuho(sentries, ientries))
+	ientries = matchable(ientries)
+	var copies int
+
+	//the parts of a split delivery are reported with their invoice entry
+	parts := make(map[Location]bool)
+	for _, s := range findSplitDeliveries(scopedShuhoEntries, ientries) {
+		for _, part := range s.parts {
+			parts[part.Location()] = true
+		}
+	}
 
 	for _, sentry := range scopedShuhoEntries {
+		if parts[sentry.Location()] {
+			continue
+		}
 		copies = 0
 
 		for _, ientry := range ientries {
-			if ientry.signature() == sentry.signature() {
+			if ientry.signature() == sentry.signature() && datesMatch(ientry, sentry) {
 				copies++
 			}
 		}
 
+		//already reported as a word count warning from the invoice side
+		if _, ok := nearWordCountMatch(sentry, ientries); copies == 0 && ok {
+			continue
+		}
+
 		if copies != 1 {
-			fmt.Printf("\033[1;31mERROR:\033[0m Shuho Entry Not in Invoice: %s\n", sentry.String())
-			totalerrors++
+			findings = append(findings, Finding{Message: printer.Sprintf("Shuho Entry Not in Invoice: %s", sentry.String()), Entry: sentry})
 		}
 	}
 
-	if totalerrors == 0 {
-		showCheckSuccess("All Shuho Entries are in the Invoice")
+	return findings
+}
+
+// ensureCancelledNotInvoiced reports invoice entries matching a cancelled
+// shuho entry and no other one of the shuho
+func ensureCancelledNotInvoiced(cancelled, sentries, ientries []Entry) []Finding {
+	var findings []Finding
+	scopedCancelled := getScopedShuho(cancelled, ientries)
+	logged := make(map[string]bool)
+	for _, sentry := range getScopedShuho(sentries, ientries) {
+		logged[sentry.signature()] = true
 	}
+
+	for _, ientry := range ientries {
+		if logged[ientry.signature()] {
+			continue
+		}
+		for _, centry := range scopedCancelled {
+			if centry.signature() == ientry.signature() && datesMatch(ientry, centry) {
+				findings = append(findings, Finding{Message: printer.Sprintf("Cancelled entry invoiced (cancelled at %s): Row %s", centry.Location(), ientry.String()), Entry: ientry})
+				break
+			}
+		}
+	}
+
+	return findings
+}
+
+// nearWordCountMatch finds an entry for the same case and type whose word count is off by one
+func nearWordCountMatch(entry Entry, candidates []Entry) (int, bool) {
+	words, err := strconv.Atoi(entry.WordCount())
+	if err != nil {
+		return 0, false
+	}
+
+	for _, candidate := range candidates {
+		if matchCase(candidate.CaseNum()) != matchCase(entry.CaseNum()) || candidate.Type() != entry.Type() {
+			continue
+		}
+		other, err := strconv.Atoi(candidate.WordCount())
+		if err != nil {
+			continue
+		}
+		if diff := words - other; diff == 1 || diff == -1 {
+			return diff, true
+		}
+	}
+
+	return 0, false
+}
+
+// datesMatch is true when dates are ignored in matching, or the shuho entry
+// was logged on the invoiced day or up to --date-tolerance days before it
+func datesMatch(ientry, sentry Entry) bool {
+	if dateTolerancef < 0 {
+		return true
+	}
+	lag := ientry.Date().Sub(sentry.Date()).Hours() / 24
+
+	return lag >= 0 && lag <= float64(dateTolerancef)
 }
 
 func getScopedShuho(sentries []Entry, ientries []Entry) []Entry {
 	var sse []Entry //scoped shuho entries
-	startDate := ientries[0].Date()
-	endDate := ientries[len(ientries)-1].Date()
+	startDate, endDate, ok := invoicePeriod(ientries)
+	if !ok {
+		return nil
+	}
+	//the first invoiced day may have been logged before it
+	if dateTolerancef > 0 {
+		startDate = startDate.AddDate(0, 0, -dateTolerancef)
+	}
 
 	for _, entry := range sentries {
 		//if the date is between the start and end dates,
@@ -451,13 +1213,11 @@ func getScopedShuho(sentries []Entry, ientries []Entry) []Entry {
 		}
 	}
 
-	//fmt.Printf("Length of sse: %d\n", len(sse))
-
 	return sse
 }
 
 func showCheckSuccess(message string) {
-	fmt.Printf("OKAY... %s\n", message)
+	fmt.Fprintf(out, "%s %s\n", markers().ok, message)
 }
 
 func sumOfChecks(entries []Entry) int {
@@ -472,6 +1232,159 @@ func sumOfChecks(entries []Entry) int {
 	return total
 }
 
+func sumOfUnknownTypes(entries []Entry) int {
+	var total int
+
+	for _, entry := range entries {
+		if !knownType(entry.Type()) {
+			total++
+		}
+	}
+
+	return total
+}
+
+// matchable leaves out entries of unknown types, their signatures mean nothing
+func matchable(entries []Entry) []Entry {
+	var known []Entry
+
+	for _, entry := range entries {
+		if knownType(entry.Type()) {
+			known = append(known, entry)
+		}
+	}
+
+	return known
+}
+
+// unknown work types are a warning, those rows are left out of the other checks
+func ensureKnownTypes(sentries []Entry, ientries []Entry) []Finding {
+	var findings []Finding
+
+	for _, entry := range append(append([]Entry(nil), ientries...), getScopedShuho(sentries, ientries)...) {
+		if !knownType(entry.Type()) {
+			findings = append(findings, Finding{Message: printer.Sprintf("Unknown type %q at %s: %s", entry.Type(), entry.Location(), entry.String()), Entry: entry, Severity: SeverityWarning})
+		}
+	}
+
+	return findings
+}
+
+// a shuho row should only fill the word count column of its type, the
+// other one is silently ignored otherwise
+func ensureSingleWordCount(sentries []Entry, ientries []Entry) []Finding {
+	var findings []Finding
+
+	for _, entry := range getScopedShuho(sentries, ientries) {
+		se, ok := entry.(ShuhoEntry)
+		if !ok || se.SCWordCount == "" || se.STWordCount == "" {
+			continue
+		}
+		findings = append(findings, Finding{
+			Message:  printer.Sprintf("Both word counts filled (check %s, translation %s) at %s: %s", se.SCWordCount, se.STWordCount, se.Location(), se.String()),
+			Entry:    se,
+			Severity: SeverityWarning,
+			Field:    "check",
+		})
+	}
+
+	return findings
+}
+
+// a word count that is empty, zero, negative or not a number would match
+// nothing or count as nothing in the totals, so it is an error in either file
+func ensureValidWordCounts(sentries []Entry, ientries []Entry) []Finding {
+	var findings []Finding
+
+	for _, e := range ientries {
+		if !positiveNumber(e.WordCount()) {
+			findings = append(findings, Finding{
+				Message:  printer.Sprintf("Word count %q is not a positive number: Row %s", e.WordCount(), e.String()),
+				Entry:    e,
+				Severity: SeverityError,
+				Field:    "words",
+			})
+		}
+	}
+	for _, e := range getScopedShuho(sentries, ientries) {
+		//unknown types have no word count column, the unknown-type check reports them
+		if e.Type() != "翻訳" && e.Type() != "英文チェック" {
+			continue
+		}
+		if !positiveNumber(e.WordCount()) {
+			findings = append(findings, Finding{
+				Message:  printer.Sprintf("Word count %q is not a positive number at %s: %s", e.WordCount(), e.Location(), e.String()),
+				Entry:    e,
+				Severity: SeverityError,
+				Field:    wordCountField(e),
+			})
+		}
+	}
+
+	return findings
+}
+
+func positiveNumber(value string) bool {
+	number, err := strconv.ParseFloat(value, 64)
+
+	return err == nil && number > 0
+}
+
+// the agency's template lists work in chronological order, and the period
+// is scoped by the first and last invoice dates
+func ensureInvoiceIsChronological(ientries []Entry) []Finding {
+	var findings []Finding
+
+	for i := 1; i < len(ientries); i++ {
+		previous, entry := ientries[i-1], ientries[i]
+		if !entry.Date().Before(previous.Date()) {
+			continue
+		}
+		findings = append(findings, Finding{
+			Message:  printer.Sprintf("Invoice entry dated before the row above it (%s): Row %s", previous.Date().Format("2006-01-02"), entry.String()),
+			Entry:    entry,
+			Severity: SeverityWarning,
+			Field:    "date",
+		})
+	}
+
+	return findings
+}
+
+// the numbering column counts 1..N down the invoice, a gap is a deleted row
+// and a repeat a copied one. Each break is reported once, numbering carries on
+// from the number found.
+func ensureContinuousRowNumbers(ientries []Entry) []Finding {
+	var findings []Finding
+	var previous int
+
+	//an invoice layout without row numbers
+	if !invoiceColumns.mapped("no") {
+		return nil
+	}
+
+	for _, entry := range ientries {
+		ie, ok := entry.(InvoiceEntry)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(ie.rowNum))
+		switch {
+		case err != nil:
+			findings = append(findings, Finding{Message: printer.Sprintf("Invoice row number %q is not a number: Row %s", ie.rowNum, ie.String()), Entry: ie, Severity: SeverityWarning})
+			previous++
+			continue
+		case n > previous+1:
+			findings = append(findings, Finding{Message: printer.Sprintf("Invoice row numbers skip from %d to %d: Row %s", previous, n, ie.String()), Entry: ie, Severity: SeverityWarning})
+		case n <= previous:
+			findings = append(findings, Finding{Message: printer.Sprintf("Invoice row number %d repeats or goes back after %d: Row %s", n, previous, ie.String()), Entry: ie, Severity: SeverityWarning})
+		}
+		previous = n
+	}
+
+	return findings
+}
+
 func sumOfTranslations(entries []Entry) int {
 	var total int
 
@@ -484,77 +1397,96 @@ func sumOfTranslations(entries []Entry) int {
 	return total
 }
 
-func parseInvoice(f *excelize.File) []Entry {
+// parseInvoice reads the entries of the last sheet, rows that fail to parse
+// are skipped and returned together as the joined error
+func parseInvoice(f Workbook) ([]Entry, error) {
+	parsedInvoiceHeader = nil
+	if export, ok := f.(*exportFile); ok {
+		return export.parse(true)
+	}
+
 	entries := make([]Entry, 0, 40)
+	var errs []error
 	var sheetName string
 
-	for _, name := range f.GetSheetList() {
+	for _, name := range f.Sheets() {
 		sheetName = name
 	}
 
-	rows, err := f.Rows(sheetName)
+	rows, err := f.SheetRows(sheetName)
 	if err != nil {
-		fmt.Println(err)
-		return entries
+		return entries, err
 	}
 
-	if rows == nil {
-		fmt.Println("No Rows")
-		return entries
+	if len(rows) == 0 {
+		return entries, fmt.Errorf("%s [%s]: no rows", f.Name(), sheetName)
 	}
-
-	dateRe := regexp.MustCompile(`\d+-\d+-\d+$`)
-	if err != nil {
-		return entries
+	//an explicit --invoice-columns wins over the layout of the header row
+	invoiceColumns = invoiceColumnsf
+	_, detected, known := detectInvoiceLayout(rows)
+	if known && !invoiceColumnsf.set {
+		invoiceColumns = detected
 	}
+	parsedInvoiceHeader = parseInvoiceHeader(f.Name(), sheetName, rows)
 
-	for rows.Next() {
+	start := invoiceSchema.start(rows)
+	for i, row := range rows {
 		var ie InvoiceEntry
-		row, err := rows.Columns()
-		if err != nil {
-			fmt.Println(err)
-			return entries
+
+		loc := Location{File: f.Name(), Sheet: sheetName, Row: i + 1}
+		row = invoiceColumns.apply(row)
+		if i < start || invoiceSchema.skips(invoiceColumns, row) {
+			continue
 		}
 
 		//no row
-		if row == nil || len(row) < 5 {
+		if rowIsBlank(row) {
 			continue
 		}
+
+		//date column cell is not a date string e.g. 06-20-23, headers and totals end up here too
+		if !invoiceDateRe.MatchString(row[3]) {
+			if row[1] != "" && isNumeric(row[4]) {
+				addParseIssue(loc, "invalid date", row)
+			}
+			continue
+		}
+
 		//not a complete row, placeholder in excel file
 		if rowNotComplete(row) {
+			addParseIssue(loc, "incomplete row", row)
 			continue
 		}
 
-		regres := dateRe.Match([]byte(row[3]))
+		var err error
+		ie.IDate, err = getDate(row[3])
 		if err != nil {
-			fmt.Println(err)
-			return entries
-		}
-		//first column cell is not a date string e.g. 6/20
-		if !regres {
+			errs = append(errs, &ParseError{loc, err})
 			continue
 		}
-
-		if len(row) > 5 {
-			ie.rowNum = row[0]
-			ie.IDate = getDate(row[3])
-			ie.ICaseNum = strings.ReplaceAll(row[1], ",", "")
-			ie.IType = row[2]
-			tmp := strings.ReplaceAll(row[4], ",", "")
-			ie.IWordCount = strings.ReplaceAll(tmp, " ", "")
-			ie.rate = row[5]
-		}
+		ie.rowNum = row[0]
+		ie.ICaseNum = strings.ReplaceAll(row[1], ",", "")
+		ie.IType = canonicalType(row[2], loc.Sheet, ie.IDate)
+		ie.IWordCount = normalizeNumber(row[4])
+		ie.rate = normalizeNumber(row[5])
+		ie.po = strings.TrimSpace(row[6])
+		ie.client = strings.TrimSpace(row[7])
+		ie.loc, ie.columns = loc, invoiceColumns
 
 		entries = append(entries, ie)
 	}
 
-	return entries
+	if len(entries) == 0 && len(errs) == 0 && !known && !invoiceColumnsf.set {
+		return entries, unknownLayoutError(f.Name(), sheetName)
+	}
+
+	return entries, errors.Join(errs...)
 }
 
 // make sure that the row has required fields
 func rowNotComplete(row []string) bool {
 	//check that each field has a value
-	if (row[0] == "") || (row[3] == "") || (row[1] == "") || (row[2] == "") || (row[4] == "") || (row[5] == "") {
+	if (row[0] == "" && invoiceColumns.mapped("no")) || (row[3] == "") || (row[1] == "") || (row[2] == "") || (row[4] == "") || (row[5] == "") {
 		return true
 	}
 
@@ -563,85 +1495,165 @@ func rowNotComplete(row []string) bool {
 
 func checkForEmptyCase(caseField string) bool {
 	//check for default casenum "ALP-" or blank casenum
-	match, _ := regexp.MatchString(`^(?i)ALP-$`, caseField)
-
-	return match && (caseField == "")
+	return emptyCaseRe.MatchString(caseField) || (caseField == "")
 }
 
 // only words for shuho entires x/x format
 func checkForValidDate(dateField string) bool {
-	match, _ := regexp.MatchString(`^(?i)\d+/\d+$`, dateField)
+	return shuhoRowDateRe.MatchString(dateField)
+}
+
+// padRow extends row to n cells, Columns() leaves off trailing empty cells
+func padRow(row []string, n int) []string {
+	for len(row) < n {
+		row = append(row, "")
+	}
 
-	return match
+	return row
 }
 
-func parseShuho(f *excelize.File) []Entry {
-	entries := make([]Entry, 0, 500)
+func rowIsBlank(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isNumeric(value string) bool {
+	_, err := strconv.ParseFloat(normalizeNumber(value), 64)
+
+	return err == nil
+}
+
+// templateSheetName tells the template sheet of a shuho by its name, it isn't always the first one
+func templateSheetName(name string) bool {
+	name = strings.ToLower(name)
+
+	return strings.Contains(name, "template") || strings.Contains(name, "テンプレ") ||
+		strings.Contains(name, "雛形") || strings.Contains(name, "ひな形")
+}
+
+// hasDatedRows is false for sheets without data, an unnamed template or a week not started yet
+func hasDatedRows(rows [][]string, columns *columnMapping) bool {
+	for _, row := range rows {
+		if checkForValidDate(columns.apply(row)[0]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseShuho(f Workbook) ([]Entry, error) {
+	if export, ok := f.(*exportFile); ok {
+		return export.parse(false)
+	}
 
-	for index, name := range f.GetSheetList() {
-		//fmt.Println("SHUHO SHEET NAME", index, name)
+	entries := make([]Entry, 0, 500)
+	var errs []error
 
-		//skip the first "template" sheet in the file
-		if index == 0 {
+	for _, name := range f.Sheets() {
+		if !sheetSelected(name) {
 			continue
 		}
 
-		rows, err := f.Rows(name)
+		rows, err := f.SheetRows(name)
 		if err != nil {
-			fmt.Println(err)
-			return entries
+			return entries, errors.Join(append(errs, err)...)
 		}
 
-		if rows == nil {
-			fmt.Printf("\033[1;31mERROR:\033[0m Sheet %s (%d) - No Rows", name, index)
-			return entries
+		columns := shuhoColumnsFor(name, rows)
+		if templateSheetName(name) || !hasDatedRows(rows, columns) {
+			continue
 		}
 
-		for rows.Next() {
+		start := shuhoSchema.start(rows)
+		var lastDate string
+		for i, row := range rows {
 			var se ShuhoEntry
 
-			row, err := rows.Columns()
-			if err != nil {
-				fmt.Println(err)
-				return entries
+			loc := Location{File: f.Name(), Sheet: name, Row: i + 1}
+			row = columns.apply(row)
+			if i < start || shuhoSchema.skips(columns, row) {
+				continue
 			}
 
 			//no row
-			if row == nil || len(row) < 6 {
+			if rowIsBlank(row) {
 				continue
 			}
 
+			cancelled := cancelledDateRe.MatchString(row[0])
+			if cancelled {
+				row[0] = strings.TrimSpace(row[0][1:])
+			}
+
+			//later rows of the same day may leave the date empty
+			if row[0] == "" && carryDatesf {
+				row[0] = lastDate
+			}
+			if checkForValidDate(row[0]) {
+				lastDate = row[0]
+			}
+
+			//headers and notes end up here too, only report rows that carry a case and a word count
 			if !checkForValidDate(row[0]) {
+				if row[1] != "" && (isNumeric(row[3]) || isNumeric(row[4])) {
+					addParseIssue(loc, "invalid date", row)
+				}
+				continue
+			}
+
+			//a day pre-filled by new-sheet with nothing done on it yet
+			if rowIsBlank(row[1:]) {
 				continue
 			}
 
 			//check for default casenum "ALP-"
 			if checkForEmptyCase(row[1]) {
+				addParseIssue(loc, "empty case number", row)
 				continue
 			}
 
 			//check that 0, 1, 2, and 6 have a value, and that 3 OR 4 has a wordcount
 			if (row[2] == "") || (row[6] == "") {
+				addParseIssue(loc, "missing type or author", row)
 				continue
 			}
 
 			//one of the two wordcounts needs to be present
 			if (row[3] == "") && (row[4] == "") {
+				addParseIssue(loc, "no word count", row)
 				continue
 			}
 
-			se.SDate = getDate(row[0])
+			var err error
+			se.SDate, err = getDate(row[0])
+			if err != nil {
+				errs = append(errs, &ParseError{loc, err})
+				continue
+			}
 			se.SCaseNum = strings.ReplaceAll(row[1], ",", "")
-			se.SType = row[2]
-			tmp := strings.ReplaceAll(row[3], ",", "")
-			se.SCWordCount = strings.ReplaceAll(tmp, " ", "")
-			tmp = strings.ReplaceAll(row[4], ",", "")
-			se.STWordCount = strings.ReplaceAll(tmp, " ", "")
+			se.SType = canonicalType(row[2], loc.Sheet, se.SDate)
+			se.SCWordCount = normalizeNumber(row[3])
+			se.STWordCount = normalizeNumber(row[4])
 			se.SAuthor = row[6]
+			se.SPO = strings.TrimSpace(row[7])
+			se.loc, se.columns = loc, columns
+
+			//cancelled jobs are kept apart, the checks only see them in the cancelled check
+			if cancelled {
+				se.Cancelled = true
+				cancelledEntries = append(cancelledEntries, se)
+				continue
+			}
 
 			entries = append(entries, se)
 		}
 	}
 
-	return entries
+	return entries, errors.Join(errs...)
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// set at build time:
+//
+//	go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%d)"
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+var versionf bool
+
+// versionString falls back on the VCS stamp go build records in the binary
+func versionString() string {
+	rev, date := commit, buildDate
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && rev == "":
+				rev = s.Value
+				if len(rev) > 12 {
+					rev = rev[:12]
+				}
+			case s.Key == "vcs.time" && date == "":
+				date = s.Value
+			}
+		}
+	}
+	if rev == "" {
+		rev = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+
+	return fmt.Sprintf("verifyshuho %s (commit %s, built %s)", version, rev, date)
+}
@@ -0,0 +1,37 @@
+//go:build js && wasm
+
+package main
+
+import "syscall/js"
+
+// The WebAssembly build verifies in the browser, web/index.html drops two
+// files onto it:
+//
+//	GOOS=js GOARCH=wasm go build -o web/verifyshuho.wasm .
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" web/
+//
+// (misc/wasm before Go 1.24). main registers
+// verifyshuho(shuho, shuhoName, invoice, invoiceName) on the page, the
+// workbooks are Uint8Arrays and it returns {report, status}.
+func init() {
+	wasmMain = serveWASM
+}
+
+func serveWASM() {
+	js.Global().Set("verifyshuho", js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) != 4 {
+			return map[string]any{"report": "verifyshuho(shuho, shuhoName, invoice, invoiceName)", "status": 2}
+		}
+
+		shuho := make([]byte, args[0].Length())
+		js.CopyBytesToGo(shuho, args[0])
+		invoice := make([]byte, args[2].Length())
+		js.CopyBytesToGo(invoice, args[2])
+
+		report, status := verifyData(args[1].String(), shuho, args[3].String(), invoice)
+		return map[string]any{"report": report, "status": status}
+	}))
+
+	//keep the exported function alive
+	select {}
+}
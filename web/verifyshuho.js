@@ -0,0 +1,40 @@
+// Loads verifyshuho.wasm and verifies the two dropped files with it.
+(async function () {
+  const go = new Go();
+  const wasm = await WebAssembly.instantiateStreaming(fetch("verifyshuho.wasm"), go.importObject);
+  go.run(wasm.instance);
+
+  const files = {};
+
+  function verify() {
+    if (!files.shuho || !files.invoice) {
+      return;
+    }
+    const result = verifyshuho(files.shuho.data, files.shuho.name, files.invoice.data, files.invoice.name);
+    const heading = document.getElementById("result");
+    heading.textContent = result.status === 0 ? "Passed" : "Failed";
+    heading.className = result.status === 0 ? "passed" : "failed";
+    document.getElementById("report").textContent = result.report;
+  }
+
+  for (const id of ["shuho", "invoice"]) {
+    const zone = document.getElementById(id);
+    zone.addEventListener("dragover", (e) => {
+      e.preventDefault();
+      zone.classList.add("over");
+    });
+    zone.addEventListener("dragleave", () => zone.classList.remove("over"));
+    zone.addEventListener("drop", async (e) => {
+      e.preventDefault();
+      zone.classList.remove("over");
+      const file = e.dataTransfer.files[0];
+      if (!file) {
+        return;
+      }
+      files[id] = { name: file.name, data: new Uint8Array(await file.arrayBuffer()) };
+      zone.textContent = file.name;
+      zone.classList.add("loaded");
+      verify();
+    });
+  }
+})();
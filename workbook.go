@@ -0,0 +1,274 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Workbook is what the parsers need from a spreadsheet file, whatever its format
+type Workbook interface {
+	Name() string
+	Sheets() []string
+	// SheetRows returns the cell text of every row, row i of the sheet at index i-1.
+	// Merged cells carry their value in every cell they cover.
+	SheetRows(sheet string) ([][]string, error)
+	Close() error
+}
+
+// stdin is where a workbook named "-" is read from
+var stdin io.Reader = os.Stdin
+
+// stdinName stands in for the file name of a piped workbook in messages
+const stdinName = "<stdin>"
+
+// openWorkbook picks the reader by file extension, "-" reads stdin
+func openWorkbook(path string) (Workbook, error) {
+	if path == "-" {
+		return readWorkbook(stdin)
+	}
+	if isRemote(path) {
+		return openRemote(path)
+	}
+	if strings.HasPrefix(path, "http://") {
+		return nil, fmt.Errorf("%s: only https:// URLs are downloaded", path)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xls":
+		return openXLS(path)
+	case ".ods":
+		return openODS(path)
+	case ".json", ".csv":
+		return openExport(path)
+	}
+
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return xlsxWorkbook{f}, nil
+}
+
+var (
+	cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	odsMimetype  = []byte("application/vnd.oasis.opendocument.spreadsheet")
+)
+
+// readWorkbook reads a piped workbook, there is no extension so the format is
+// told from the content: .xls is an OLE compound document and .ods a zip
+// starting with its uncompressed mimetype, anything else is taken as .xlsx
+func readWorkbook(r io.Reader) (Workbook, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", stdinName, err)
+	}
+	readChecksums["-"] = fmt.Sprintf("%x", sha256.Sum256(data))
+
+	return workbookFromData(stdinName, data)
+}
+
+// dataWorkbook is the workbook or exported entries held in data, told apart by the extension of name
+func dataWorkbook(name string, data []byte) (Workbook, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".csv":
+		return readExport(name, bytes.NewReader(data))
+	}
+
+	return workbookFromData(name, data)
+}
+
+// workbookFromData is the workbook held in data, named name
+func workbookFromData(name string, data []byte) (Workbook, error) {
+	head := data
+	if len(head) > 128 {
+		head = head[:128]
+	}
+
+	switch {
+	case bytes.HasPrefix(data, cfbSignature):
+		return readXLS(name, bytes.NewReader(data))
+	case bytes.Contains(head, odsMimetype):
+		z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return readODS(name, z)
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	f.Path = name
+
+	return xlsxWorkbook{f}, nil
+}
+
+// xlsxWorkbook reads .xlsx files through excelize
+type xlsxWorkbook struct {
+	f *excelize.File
+}
+
+func (w xlsxWorkbook) Name() string {
+	return w.f.Path
+}
+
+func (w xlsxWorkbook) Sheets() []string {
+	return w.f.GetSheetList()
+}
+
+func (w xlsxWorkbook) Close() error {
+	return w.f.Close()
+}
+
+// formulas are only evaluated in the leading columns the parsers read
+const formulaColumns = 8
+
+func (w xlsxWorkbook) SheetRows(sheet string) ([][]string, error) {
+	var result [][]string
+
+	rows, err := w.f.Rows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	merged, err := mergedCells(w.f, sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		row, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+
+		loc := Location{File: w.Name(), Sheet: sheet, Row: len(result) + 1}
+		row = fillMergedCells(padRow(row, formulaColumns), merged, loc.Row)
+		row = fillFormulaCells(w.f, sheet, loc, row)
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+// mergedCells maps every cell covered by a merged range, except the top-left
+// one holding the value, to that value. Rows() only reports it once.
+func mergedCells(f *excelize.File, sheet string) (map[string]string, error) {
+	merged := make(map[string]string)
+
+	ranges, err := f.GetMergeCells(sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mc := range ranges {
+		startCol, startRow, err := excelize.CellNameToCoordinates(mc.GetStartAxis())
+		if err != nil {
+			return nil, err
+		}
+		endCol, endRow, err := excelize.CellNameToCoordinates(mc.GetEndAxis())
+		if err != nil {
+			return nil, err
+		}
+
+		for c := startCol; c <= endCol; c++ {
+			for r := startRow; r <= endRow; r++ {
+				if c == startCol && r == startRow {
+					continue
+				}
+				cell, _ := excelize.CoordinatesToCellName(c, r)
+				merged[cell] = mc.GetCellValue()
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// fillMergedCells puts the merged value into the empty cells of row rowIndex
+func fillMergedCells(row []string, merged map[string]string, rowIndex int) []string {
+	for c := range row {
+		if row[c] != "" {
+			continue
+		}
+		cell, _ := excelize.CoordinatesToCellName(c+1, rowIndex)
+		if value, ok := merged[cell]; ok {
+			row[c] = value
+		}
+	}
+
+	return row
+}
+
+// fillFormulaCells evaluates formulas in the empty cells of row, files saved
+// without cached values only keep the formula and Columns() comes back empty
+func fillFormulaCells(f *excelize.File, sheet string, loc Location, row []string) []string {
+	for c := range row {
+		if row[c] != "" {
+			continue
+		}
+		cell, _ := excelize.CoordinatesToCellName(c+1, loc.Row)
+		formula, err := f.GetCellFormula(sheet, cell)
+		if err != nil || formula == "" {
+			continue
+		}
+
+		value, err := f.CalcCellValue(sheet, cell)
+		if err != nil {
+			addParseIssue(loc, fmt.Sprintf("formula %s in %s: %s", formula, cell, err), row)
+			continue
+		}
+		row[c] = value
+	}
+
+	return row
+}
+
+// cellRange is a merged range of zero based rows and columns, the last ones included
+type cellRange struct {
+	firstRow, lastRow, firstCol, lastCol int
+}
+
+// setCell grows rows as needed to set the cell at row, col
+func setCell(rows [][]string, row, col int, text string) [][]string {
+	for len(rows) <= row {
+		rows = append(rows, nil)
+	}
+	for len(rows[row]) <= col {
+		rows[row] = append(rows[row], "")
+	}
+	rows[row][col] = text
+
+	return rows
+}
+
+// fillMergedRanges copies the value of the top-left cell of every range to the empty cells it covers
+func fillMergedRanges(rows [][]string, ranges []cellRange) [][]string {
+	for _, m := range ranges {
+		if m.firstRow >= len(rows) || m.firstCol >= len(rows[m.firstRow]) {
+			continue
+		}
+		value := rows[m.firstRow][m.firstCol]
+		for r := m.firstRow; r <= m.lastRow; r++ {
+			for c := m.firstCol; c <= m.lastCol; c++ {
+				if r < len(rows) && c < len(rows[r]) && rows[r][c] != "" {
+					continue
+				}
+				rows = setCell(rows, r, c, value)
+			}
+		}
+	}
+
+	return rows
+}
@@ -0,0 +1,600 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// xlsWorkbook is a legacy Excel 97-2003 (BIFF8) workbook. Only what the
+// parsers need is read: cell text of the worksheets and merged ranges,
+// numbers in date formats are rendered as dates the way excelize does.
+type xlsWorkbook struct {
+	path   string
+	sheets []string
+	rows   map[string][][]string
+}
+
+func (w *xlsWorkbook) Name() string {
+	return w.path
+}
+
+func (w *xlsWorkbook) Sheets() []string {
+	return w.sheets
+}
+
+func (w *xlsWorkbook) SheetRows(sheet string) ([][]string, error) {
+	rows, ok := w.rows[sheet]
+	if !ok {
+		return nil, fmt.Errorf("sheet %s does not exist", sheet)
+	}
+
+	return rows, nil
+}
+
+func (w *xlsWorkbook) Close() error {
+	return nil
+}
+
+// BIFF8 record types
+const (
+	xlsFormula    = 0x0006
+	xlsEOF        = 0x000A
+	xlsDateMode   = 0x0022
+	xlsContinue   = 0x003C
+	xlsBoundSheet = 0x0085
+	xlsMulRK      = 0x00BD
+	xlsXF         = 0x00E0
+	xlsMergeCells = 0x00E5
+	xlsSST        = 0x00FC
+	xlsLabelSST   = 0x00FD
+	xlsNumber     = 0x0203
+	xlsLabel      = 0x0204
+	xlsBoolErr    = 0x0205
+	xlsString     = 0x0207
+	xlsRK         = 0x027E
+	xlsFormat     = 0x041E
+	xlsBOF        = 0x0809
+)
+
+var errNotBIFF8 = errors.New("not an Excel 97-2003 workbook")
+
+func openXLS(path string) (Workbook, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readXLS(path, f)
+}
+
+// readXLS reads the workbook stream out of the compound document in r
+func readXLS(path string, r io.ReaderAt) (Workbook, error) {
+	doc, err := mscfb.New(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var stream []byte
+	for entry, err := doc.Next(); err == nil; entry, err = doc.Next() {
+		if entry.Name == "Workbook" || entry.Name == "Book" {
+			if stream, err = io.ReadAll(entry); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			break
+		}
+	}
+	if stream == nil {
+		return nil, fmt.Errorf("%s: %w", path, errNotBIFF8)
+	}
+
+	w := &xlsWorkbook{path: path, rows: make(map[string][][]string)}
+	if err := w.read(stream); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return w, nil
+}
+
+type xlsRecord struct {
+	kind   uint16
+	offset int
+	data   []byte
+}
+
+func xlsRecords(stream []byte) ([]xlsRecord, error) {
+	var records []xlsRecord
+
+	for pos := 0; pos+4 <= len(stream); {
+		kind := binary.LittleEndian.Uint16(stream[pos:])
+		size := int(binary.LittleEndian.Uint16(stream[pos+2:]))
+		if pos+4+size > len(stream) {
+			return nil, fmt.Errorf("truncated record %#x at %d", kind, pos)
+		}
+		records = append(records, xlsRecord{kind, pos, stream[pos+4 : pos+4+size]})
+		pos += 4 + size
+	}
+
+	return records, nil
+}
+
+// xlsCell is a cell value before it is rendered, numbers need the XF
+// records of the globals to know whether they are dates
+type xlsCell struct {
+	row, col int
+	text     string
+	number   float64
+	isNumber bool
+	xf       int
+}
+
+type xlsSheet struct {
+	name   string
+	cells  []xlsCell
+	merged []cellRange
+}
+
+func (w *xlsWorkbook) read(stream []byte) error {
+	records, err := xlsRecords(stream)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 || records[0].kind != xlsBOF || len(records[0].data) < 4 ||
+		binary.LittleEndian.Uint16(records[0].data) != 0x0600 {
+		return errNotBIFF8
+	}
+
+	var sst []string
+	var xfFormats []int
+	formats := make(map[int]string)
+	var date1904 bool
+	sheetAt := make(map[int]*xlsSheet)
+	var sheets []*xlsSheet
+
+	var current *xlsSheet
+	//cell of the last FORMULA with a text result, its STRING record follows it
+	pending := -1
+
+	for i := 0; i < len(records); i++ {
+		r := records[i]
+		d := r.data
+
+		//CONTINUE records extend the one before them
+		var continues [][]byte
+		for i+1 < len(records) && records[i+1].kind == xlsContinue {
+			i++
+			continues = append(continues, records[i].data)
+		}
+
+		switch r.kind {
+		case xlsBOF:
+			current = sheetAt[r.offset]
+		case xlsEOF:
+			current = nil
+		case xlsBoundSheet:
+			if len(d) < 8 {
+				continue
+			}
+			//skip chart and macro sheets
+			if d[5] != 0 {
+				continue
+			}
+			s := &xlsSheet{name: xlsShortString(d[6:])}
+			sheetAt[int(binary.LittleEndian.Uint32(d))] = s
+			sheets = append(sheets, s)
+		case xlsSST:
+			sst = readSST(append([][]byte{d}, continues...))
+		case xlsXF:
+			if len(d) >= 4 {
+				xfFormats = append(xfFormats, int(binary.LittleEndian.Uint16(d[2:])))
+			}
+		case xlsFormat:
+			if len(d) >= 5 {
+				formats[int(binary.LittleEndian.Uint16(d))], _ = xlsString16(d[2:])
+			}
+		case xlsDateMode:
+			date1904 = len(d) >= 2 && binary.LittleEndian.Uint16(d) == 1
+		}
+
+		if current != nil && r.kind == xlsString && pending >= 0 {
+			current.cells[pending].text, _ = xlsString16(d)
+			pending = -1
+		}
+		if current == nil || len(d) < 6 {
+			continue
+		}
+		row, col, xf := int(binary.LittleEndian.Uint16(d)), int(binary.LittleEndian.Uint16(d[2:])), int(binary.LittleEndian.Uint16(d[4:]))
+
+		switch r.kind {
+		case xlsLabelSST:
+			if len(d) < 10 {
+				continue
+			}
+			if n := int(binary.LittleEndian.Uint32(d[6:])); n < len(sst) {
+				current.cells = append(current.cells, xlsCell{row: row, col: col, text: sst[n]})
+			}
+		case xlsLabel:
+			text, _ := xlsString16(d[6:])
+			current.cells = append(current.cells, xlsCell{row: row, col: col, text: text})
+		case xlsNumber:
+			if len(d) >= 14 {
+				current.cells = append(current.cells, xlsCell{row: row, col: col, number: math.Float64frombits(binary.LittleEndian.Uint64(d[6:])), isNumber: true, xf: xf})
+			}
+		case xlsRK:
+			if len(d) >= 10 {
+				current.cells = append(current.cells, xlsCell{row: row, col: col, number: xlsRKNumber(binary.LittleEndian.Uint32(d[6:])), isNumber: true, xf: xf})
+			}
+		case xlsMulRK:
+			for pos := 4; pos+6 <= len(d)-2; pos += 6 {
+				current.cells = append(current.cells, xlsCell{row: row, col: col, number: xlsRKNumber(binary.LittleEndian.Uint32(d[pos+2:])), isNumber: true,
+					xf: int(binary.LittleEndian.Uint16(d[pos:]))})
+				col++
+			}
+		case xlsBoolErr:
+			if len(d) >= 8 && d[7] == 0 {
+				current.cells = append(current.cells, xlsCell{row: row, col: col, text: strings.ToUpper(strconv.FormatBool(d[6] != 0))})
+			}
+		case xlsFormula:
+			if len(d) < 14 {
+				continue
+			}
+			result := d[6:14]
+			if result[6] != 0xFF || result[7] != 0xFF {
+				current.cells = append(current.cells, xlsCell{row: row, col: col, number: math.Float64frombits(binary.LittleEndian.Uint64(result)), isNumber: true, xf: xf})
+				continue
+			}
+			switch result[0] {
+			case 0:
+				//the text result follows in a STRING record
+				current.cells = append(current.cells, xlsCell{row: row, col: col})
+				pending = len(current.cells) - 1
+			case 1:
+				current.cells = append(current.cells, xlsCell{row: row, col: col, text: strings.ToUpper(strconv.FormatBool(result[2] != 0))})
+			}
+		case xlsMergeCells:
+			count := int(binary.LittleEndian.Uint16(d))
+			for n := 0; n < count && 2+n*8+8 <= len(d); n++ {
+				ref := d[2+n*8:]
+				current.merged = append(current.merged, cellRange{
+					int(binary.LittleEndian.Uint16(ref)), int(binary.LittleEndian.Uint16(ref[2:])),
+					int(binary.LittleEndian.Uint16(ref[4:])), int(binary.LittleEndian.Uint16(ref[6:])),
+				})
+			}
+		}
+	}
+
+	for _, s := range sheets {
+		w.sheets = append(w.sheets, s.name)
+		w.rows[s.name] = s.render(xfFormats, formats, date1904)
+	}
+
+	return nil
+}
+
+// render lays the cells out as rows, fills merged ranges and formats numbers
+func (s *xlsSheet) render(xfFormats []int, formats map[int]string, date1904 bool) [][]string {
+	var rows [][]string
+
+	for _, c := range s.cells {
+		text := c.text
+		if c.isNumber {
+			var format string
+			id := -1
+			if c.xf < len(xfFormats) {
+				id = xfFormats[c.xf]
+				format = formats[id]
+			}
+			text = xlsFormatNumber(c.number, id, format, date1904)
+		}
+		if text != "" {
+			rows = setCell(rows, c.row, c.col, text)
+		}
+	}
+
+	return fillMergedRanges(rows, s.merged)
+}
+
+// layouts of the builtin date formats, as excelize renders them
+var xlsBuiltinDateLayouts = map[int]string{
+	14: "01-02-06",
+	15: "2-Jan-06",
+	16: "2-Jan",
+	17: "Jan-06",
+	18: "3:04 PM",
+	19: "3:04:05 PM",
+	20: "15:04",
+	21: "15:04:05",
+	22: "1/2/06 15:04",
+	45: "04:05",
+	46: "15:04:05",
+	47: "04:05",
+}
+
+func xlsFormatNumber(n float64, id int, format string, date1904 bool) string {
+	layout, ok := xlsBuiltinDateLayouts[id]
+	if !ok && format != "" {
+		layout, ok = xlsDateLayout(format)
+	}
+	if !ok {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(n)
+	seconds := math.Round((n - days) * 86400)
+
+	return epoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second).Format(layout)
+}
+
+var xlsDateTokens = []struct{ token, layout string }{
+	{"yyyy", "2006"}, {"yy", "06"},
+	{"mmmm", "January"}, {"mmm", "Jan"}, {"mm", "01"}, {"m", "1"},
+	{"dddd", "Monday"}, {"ddd", "Mon"}, {"dd", "02"}, {"d", "2"},
+	{"hh", "15"}, {"h", "15"}, {"ss", "05"}, {"s", "5"},
+}
+
+// xlsDateLayout turns a custom number format such as m/d or yyyy-mm-dd into a
+// Go layout, formats without day, month or year tokens aren't dates
+func xlsDateLayout(format string) (string, bool) {
+	var layout strings.Builder
+	var date, clock bool
+
+	//only the positive section counts
+	format, _, _ = strings.Cut(format, ";")
+	for i := 0; i < len(format); {
+		rest := strings.ToLower(format[i:])
+		switch {
+		case rest[0] == '"':
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			layout.WriteString(format[i+1 : i+1+end])
+			i += end + 2
+			continue
+		case rest[0] == '\\' && len(rest) > 1:
+			layout.WriteByte(format[i+1])
+			i += 2
+			continue
+		case rest[0] == '[':
+			//colors and locales
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return "", false
+			}
+			i += end + 1
+			continue
+		case rest[0] == '0' || rest[0] == '#' || rest[0] == '@':
+			return "", false
+		}
+
+		matched := false
+		for _, t := range xlsDateTokens {
+			if !strings.HasPrefix(rest, t.token) {
+				continue
+			}
+			value := t.layout
+			switch {
+			case t.token[0] == 'h' || t.token[0] == 's':
+				clock = true
+			case strings.HasPrefix(t.token, "m") && len(t.token) <= 2 && clock:
+				//m right after an hour is minutes
+				value = map[string]string{"m": "4", "mm": "04"}[t.token]
+			default:
+				date = true
+			}
+			layout.WriteString(value)
+			i += len(t.token)
+			matched = true
+			break
+		}
+		if !matched {
+			layout.WriteByte(format[i])
+			i++
+		}
+	}
+
+	return layout.String(), date
+}
+
+func xlsRKNumber(rk uint32) float64 {
+	var n float64
+	if rk&0x02 != 0 {
+		n = float64(int32(rk) >> 2)
+	} else {
+		n = math.Float64frombits(uint64(rk&0xFFFFFFFC) << 32)
+	}
+	if rk&0x01 != 0 {
+		n /= 100
+	}
+
+	return n
+}
+
+func xlsChars(d []byte, count int, high bool) string {
+	if !high {
+		if count > len(d) {
+			count = len(d)
+		}
+		runes := make([]rune, count)
+		for i, b := range d[:count] {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+
+	if 2*count > len(d) {
+		count = len(d) / 2
+	}
+	units := make([]uint16, count)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(d[2*i:])
+	}
+
+	return string(utf16.Decode(units))
+}
+
+func xlsShortString(d []byte) string {
+	if len(d) < 2 {
+		return ""
+	}
+
+	return xlsChars(d[2:], int(d[0]), d[1]&0x01 != 0)
+}
+
+// xlsString16 reads a string with a 16 bit length and returns it with the bytes it took
+func xlsString16(d []byte) (string, int) {
+	if len(d) < 3 {
+		return "", len(d)
+	}
+	count, high := int(binary.LittleEndian.Uint16(d)), d[2]&0x01 != 0
+	size := count
+	if high {
+		size *= 2
+	}
+
+	return xlsChars(d[3:], count, high), 3 + size
+}
+
+// sstReader reads the shared strings across CONTINUE records, a string split
+// over two records gets a new flags byte at the start of the second one
+type sstReader struct {
+	chunks [][]byte
+	chunk  int
+	pos    int
+}
+
+func (r *sstReader) next() bool {
+	for r.chunk < len(r.chunks) && r.pos >= len(r.chunks[r.chunk]) {
+		r.chunk++
+		r.pos = 0
+	}
+
+	return r.chunk < len(r.chunks)
+}
+
+func (r *sstReader) bytes(n int) []byte {
+	var buf bytes.Buffer
+	for n > 0 && r.next() {
+		d := r.chunks[r.chunk][r.pos:]
+		if len(d) > n {
+			d = d[:n]
+		}
+		buf.Write(d)
+		r.pos += len(d)
+		n -= len(d)
+	}
+
+	return buf.Bytes()
+}
+
+func (r *sstReader) uint16() int {
+	d := r.bytes(2)
+	if len(d) < 2 {
+		return 0
+	}
+
+	return int(binary.LittleEndian.Uint16(d))
+}
+
+func (r *sstReader) uint32() int {
+	d := r.bytes(4)
+	if len(d) < 4 {
+		return 0
+	}
+
+	return int(binary.LittleEndian.Uint32(d))
+}
+
+func (r *sstReader) string() string {
+	count := r.uint16()
+	flags := r.bytes(1)
+	if len(flags) == 0 {
+		return ""
+	}
+
+	var runs, ext int
+	if flags[0]&0x08 != 0 {
+		runs = r.uint16()
+	}
+	if flags[0]&0x04 != 0 {
+		ext = r.uint32()
+	}
+
+	var text strings.Builder
+	high := flags[0]&0x01 != 0
+	chunk := r.chunk
+	for count > 0 && r.next() {
+		//a CONTINUE record starts with the flags of the rest of the string
+		if r.chunk != chunk {
+			flags := r.bytes(1)
+			if len(flags) == 0 {
+				break
+			}
+			high = flags[0]&0x01 != 0
+			chunk = r.chunk
+			continue
+		}
+		d := r.chunks[r.chunk][r.pos:]
+		n := len(d)
+		if high {
+			n /= 2
+		}
+		//half a character left in the record, a broken file
+		if n == 0 {
+			r.pos = len(r.chunks[r.chunk])
+			continue
+		}
+		if n > count {
+			n = count
+		}
+		text.WriteString(xlsChars(d, n, high))
+		if high {
+			r.pos += 2 * n
+		} else {
+			r.pos += n
+		}
+		count -= n
+	}
+
+	//formatting runs and phonetic data
+	r.bytes(4*runs + ext)
+
+	return text.String()
+}
+
+func readSST(chunks [][]byte) []string {
+	r := &sstReader{chunks: chunks}
+	r.bytes(4)
+	unique := r.uint32()
+
+	//the count comes from the file, a string takes at least 3 bytes
+	var size int
+	for _, chunk := range chunks {
+		size += len(chunk)
+	}
+	capacity := unique
+	if capacity > size/3 {
+		capacity = size / 3
+	}
+	strs := make([]string, 0, capacity)
+	for len(strs) < unique && r.next() {
+		strs = append(strs, r.string())
+	}
+
+	return strs
+}